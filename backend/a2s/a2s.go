@@ -0,0 +1,335 @@
+package a2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// Source 引擎查询协议（A2S）客户端，用于读取服务端对外公布的名称、地图和在线人数
+// 协议说明: https://developer.valvesoftware.com/wiki/Server_queries
+
+const (
+	headerSingle = 0xFFFFFFFF // 单包响应
+	headerSplit  = 0xFFFFFFFE // 分包响应
+
+	reqInfo       = 0x54
+	reqPlayer     = 0x55
+	respInfo      = 0x49
+	respPlayer    = 0x44
+	respChallenge = 0x41
+
+	maxChallenges = 3 // 服务端反复要求 challenge 时的最大重试次数
+)
+
+// EDF 标志位，表示 A2S_INFO 响应末尾包含的可选字段
+const (
+	edfPort     = 0x80
+	edfSteamID  = 0x10
+	edfSourceTV = 0x40
+	edfKeywords = 0x20
+	edfGameID   = 0x01
+)
+
+var (
+	ErrTimeout    = errors.New("A2S 查询超时")
+	ErrCompressed = errors.New("不支持压缩的 A2S 分包响应")
+)
+
+// Info A2S_INFO 响应
+type Info struct {
+	Protocol    uint8
+	Name        string
+	Map         string
+	Folder      string
+	Game        string
+	AppID       uint16
+	Players     int
+	MaxPlayers  int
+	Bots        int
+	ServerType  string // d: 独立服务端 / l: 非独立 / p: SourceTV
+	Environment string // l: Linux / w: Windows / m: macOS
+	Password    bool
+	VAC         bool
+	Version     string
+	Port        uint16
+	SteamID     uint64
+	Keywords    string
+	GameID      uint64
+}
+
+// Player A2S_PLAYER 响应中的一名玩家
+type Player struct {
+	Index    uint8
+	Name     string
+	Score    int32
+	Duration float32 // 在线秒数
+}
+
+// Client 对单个服务端的查询连接，非并发安全
+type Client struct {
+	conn    *net.UDPConn
+	timeout time.Duration
+}
+
+// Dial 建立到 address（host:port）的 UDP 查询连接
+func Dial(address string, timeout time.Duration) (*Client, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, timeout: timeout}, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Info 查询 A2S_INFO，服务端要求 challenge 时自动重发
+func (c *Client) Info() (*Info, error) {
+	req := append([]byte{0xFF, 0xFF, 0xFF, 0xFF, reqInfo}, []byte("Source Engine Query\x00")...)
+	payload, err := c.request(req, respInfo, func(challenge []byte) []byte {
+		return append(append([]byte{}, req...), challenge...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(payload)
+}
+
+// Players 查询 A2S_PLAYER，先以 -1 请求 challenge
+func (c *Client) Players() ([]Player, error) {
+	build := func(challenge []byte) []byte {
+		return append([]byte{0xFF, 0xFF, 0xFF, 0xFF, reqPlayer}, challenge...)
+	}
+	payload, err := c.request(build([]byte{0xFF, 0xFF, 0xFF, 0xFF}), respPlayer, build)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlayers(payload)
+}
+
+// request 发送请求并等待指定类型的响应，返回类型字节之后的内容
+func (c *Client) request(req []byte, want byte, withChallenge func([]byte) []byte) ([]byte, error) {
+	for i := 0; i <= maxChallenges; i++ {
+		if _, err := c.conn.Write(req); err != nil {
+			return nil, err
+		}
+		packet, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		if len(packet) == 0 {
+			return nil, errors.New("A2S 响应为空")
+		}
+		switch packet[0] {
+		case want:
+			return packet[1:], nil
+		case respChallenge:
+			if len(packet) < 5 {
+				return nil, errors.New("A2S challenge 响应不完整")
+			}
+			req = withChallenge(packet[1:5])
+		default:
+			return nil, fmt.Errorf("意外的 A2S 响应类型: 0x%02X", packet[0])
+		}
+	}
+	return nil, errors.New("A2S 服务端反复要求 challenge")
+}
+
+// receive 读取一个完整响应（合并分包），返回去掉包头后的内容
+func (c *Client) receive() ([]byte, error) {
+	buf := make([]byte, 65535)
+	var parts [][]byte
+	var got, total int
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, ErrTimeout
+			}
+			return nil, err
+		}
+		if n < 4 {
+			return nil, errors.New("A2S 响应过短")
+		}
+		data := buf[:n]
+		switch binary.LittleEndian.Uint32(data) {
+		case headerSingle:
+			return append([]byte{}, data[4:]...), nil
+		case headerSplit:
+			// ID(4) 总包数(1) 序号(1) 分包大小(2)
+			if n < 12 {
+				return nil, errors.New("A2S 分包头不完整")
+			}
+			if binary.LittleEndian.Uint32(data[4:])&0x80000000 != 0 {
+				return nil, ErrCompressed
+			}
+			count, index := int(data[8]), int(data[9])
+			if count == 0 || index >= count {
+				return nil, errors.New("A2S 分包序号无效")
+			}
+			if parts == nil {
+				parts, total = make([][]byte, count), count
+			}
+			if count != total {
+				return nil, errors.New("A2S 分包数量不一致")
+			}
+			if parts[index] == nil {
+				parts[index] = append([]byte{}, data[12:]...)
+				got++
+			}
+			if got == total {
+				payload := bytes.Join(parts, nil)
+				// 合并后的内容仍以单包头开头
+				if len(payload) < 4 || binary.LittleEndian.Uint32(payload) != headerSingle {
+					return nil, errors.New("A2S 分包内容无效")
+				}
+				return payload[4:], nil
+			}
+		default:
+			return nil, errors.New("未知的 A2S 包头")
+		}
+	}
+}
+
+// reader 按 A2S 格式顺序读取字段，越界时记录错误
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errors.New("A2S 响应被截断")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = errors.New("A2S 字符串未结束")
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+// parseInfo 解析 A2S_INFO 响应内容（类型字节之后）
+func parseInfo(payload []byte) (*Info, error) {
+	r := &reader{data: payload}
+	info := &Info{
+		Protocol: r.byte(),
+		Name:     r.string(),
+		Map:      r.string(),
+		Folder:   r.string(),
+		Game:     r.string(),
+		AppID:    r.uint16(),
+	}
+	info.Players = int(r.byte())
+	info.MaxPlayers = int(r.byte())
+	info.Bots = int(r.byte())
+	info.ServerType = string(rune(r.byte()))
+	info.Environment = string(rune(r.byte()))
+	info.Password = r.byte() == 1
+	info.VAC = r.byte() == 1
+	info.Version = r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// 可选字段，旧服务端可能没有 EDF
+	if len(r.data) == 0 {
+		return info, nil
+	}
+	edf := r.byte()
+	if edf&edfPort != 0 {
+		info.Port = r.uint16()
+	}
+	if edf&edfSteamID != 0 {
+		info.SteamID = r.uint64()
+	}
+	if edf&edfSourceTV != 0 {
+		r.uint16()
+		r.string()
+	}
+	if edf&edfKeywords != 0 {
+		info.Keywords = r.string()
+	}
+	if edf&edfGameID != 0 {
+		info.GameID = r.uint64()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return info, nil
+}
+
+// parsePlayers 解析 A2S_PLAYER 响应内容（类型字节之后）
+func parsePlayers(payload []byte) ([]Player, error) {
+	r := &reader{data: payload}
+	count := int(r.byte())
+	players := make([]Player, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		p := Player{Index: r.byte(), Name: r.string()}
+		p.Score = int32(r.uint32())
+		p.Duration = math.Float32frombits(r.uint32())
+		if r.err == nil {
+			players = append(players, p)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return players, nil
+}
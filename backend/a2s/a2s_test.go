@@ -0,0 +1,287 @@
+package a2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testChallenge = []byte{0x11, 0x22, 0x33, 0x44}
+
+// fakeServer 本地 UDP 响应端：对每个请求调用 handler，依次发送返回的数据包
+type fakeServer struct {
+	mu       sync.Mutex
+	requests [][]byte
+}
+
+func startFakeServer(t *testing.T, handler func(req []byte) [][]byte) (*fakeServer, string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	srv := &fakeServer{}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			req := append([]byte{}, buf[:n]...)
+			srv.mu.Lock()
+			srv.requests = append(srv.requests, req)
+			srv.mu.Unlock()
+			for _, packet := range handler(req) {
+				conn.WriteToUDP(packet, addr)
+			}
+		}
+	}()
+	return srv, conn.LocalAddr().String()
+}
+
+func (s *fakeServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func dialTest(t *testing.T, address string, timeout time.Duration) *Client {
+	t.Helper()
+	client, err := Dial(address, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// payloadBuilder 按 A2S 格式拼接响应内容
+type payloadBuilder struct{ bytes.Buffer }
+
+func (b *payloadBuilder) str(s string) *payloadBuilder {
+	b.WriteString(s)
+	b.WriteByte(0)
+	return b
+}
+
+func (b *payloadBuilder) u8(v uint8) *payloadBuilder {
+	b.WriteByte(v)
+	return b
+}
+
+func (b *payloadBuilder) le(v interface{}) *payloadBuilder {
+	binary.Write(&b.Buffer, binary.LittleEndian, v)
+	return b
+}
+
+func single(payload []byte) []byte {
+	return append([]byte{0xFF, 0xFF, 0xFF, 0xFF}, payload...)
+}
+
+// split 将单包响应按 size 切分为多个分包
+func split(id uint32, packet []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(packet) > 0 {
+		n := size
+		if len(packet) < n {
+			n = len(packet)
+		}
+		chunks = append(chunks, packet[:n])
+		packet = packet[n:]
+	}
+	packets := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		b := &payloadBuilder{}
+		b.le(uint32(headerSplit)).le(id).u8(uint8(len(chunks))).u8(uint8(i)).le(uint16(size))
+		b.Write(chunk)
+		packets[i] = b.Bytes()
+	}
+	return packets
+}
+
+func challengePacket() []byte {
+	return single(append([]byte{respChallenge}, testChallenge...))
+}
+
+func hasChallenge(req []byte) bool {
+	return bytes.HasSuffix(req, testChallenge)
+}
+
+// infoPayload A2S_INFO 响应内容（含类型字节），edf 为 0 时不含可选字段
+func infoPayload(edf uint8) []byte {
+	b := &payloadBuilder{}
+	b.u8(respInfo).u8(17).str("ARSM Test").str("Everon").str("arma").str("Arma Reforger")
+	b.le(uint16(0)).u8(12).u8(64).u8(0).u8('d').u8('l').u8(1).u8(0).str("1.2.0.0")
+	if edf == 0 {
+		return b.Bytes()
+	}
+	b.u8(edf)
+	if edf&edfPort != 0 {
+		b.le(uint16(2001))
+	}
+	if edf&edfSteamID != 0 {
+		b.le(uint64(90000000000000001))
+	}
+	if edf&edfSourceTV != 0 {
+		b.le(uint16(27020)).str("SourceTV")
+	}
+	if edf&edfKeywords != 0 {
+		b.str("conflict,pve")
+	}
+	if edf&edfGameID != 0 {
+		b.le(uint64(1874880))
+	}
+	return b.Bytes()
+}
+
+func playersPayload() []byte {
+	b := &payloadBuilder{}
+	b.u8(respPlayer).u8(2)
+	b.u8(0).str("Alpha").le(int32(12)).le(math.Float32bits(61.5))
+	b.u8(1).str("Bravo").le(int32(-3)).le(math.Float32bits(5))
+	return b.Bytes()
+}
+
+func TestInfoChallenge(t *testing.T) {
+	srv, addr := startFakeServer(t, func(req []byte) [][]byte {
+		if req[4] != reqInfo || !bytes.Contains(req, []byte("Source Engine Query\x00")) {
+			return nil
+		}
+		if !hasChallenge(req) {
+			return [][]byte{challengePacket()}
+		}
+		return [][]byte{single(infoPayload(edfPort | edfSteamID | edfSourceTV | edfKeywords | edfGameID))}
+	})
+
+	info, err := dialTest(t, addr, time.Second).Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Info{
+		Protocol: 17, Name: "ARSM Test", Map: "Everon", Folder: "arma", Game: "Arma Reforger",
+		Players: 12, MaxPlayers: 64, ServerType: "d", Environment: "l",
+		Password: true, Version: "1.2.0.0",
+		Port: 2001, SteamID: 90000000000000001, Keywords: "conflict,pve", GameID: 1874880,
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got  %+v\nwant %+v", info, want)
+	}
+	if n := srv.requestCount(); n != 2 {
+		t.Errorf("requests = %d, want 2 (challenge handshake)", n)
+	}
+}
+
+func TestInfoOptionalFields(t *testing.T) {
+	tests := []struct {
+		name string
+		edf  uint8
+		want Info
+	}{
+		{"no edf", 0, Info{}},
+		{"port only", edfPort, Info{Port: 2001}},
+		{"sourcetv skipped", edfSourceTV | edfKeywords, Info{Keywords: "conflict,pve"}},
+		{"steam and game id", edfSteamID | edfGameID, Info{SteamID: 90000000000000001, GameID: 1874880}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseInfo(infoPayload(tt.edf)[1:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Info{Port: info.Port, SteamID: info.SteamID, Keywords: info.Keywords, GameID: info.GameID}
+			if got != tt.want || info.Version != "1.2.0.0" {
+				t.Errorf("got %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayersChallengeSplit(t *testing.T) {
+	srv, addr := startFakeServer(t, func(req []byte) [][]byte {
+		if req[4] != reqPlayer {
+			return nil
+		}
+		if !hasChallenge(req) {
+			if !bytes.Equal(req[5:], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+				return nil
+			}
+			return [][]byte{challengePacket()}
+		}
+		// 分包乱序到达，并重复发送其中一个
+		packets := split(7, single(playersPayload()), 8)
+		out := [][]byte{packets[0]}
+		for i := len(packets) - 1; i >= 0; i-- {
+			out = append(out, packets[i])
+		}
+		return out
+	})
+
+	players, err := dialTest(t, addr, time.Second).Players()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Player{
+		{Index: 0, Name: "Alpha", Score: 12, Duration: 61.5},
+		{Index: 1, Name: "Bravo", Score: -3, Duration: 5},
+	}
+	if !reflect.DeepEqual(players, want) {
+		t.Errorf("got %+v, want %+v", players, want)
+	}
+	if n := srv.requestCount(); n != 2 {
+		t.Errorf("requests = %d, want 2 (challenge handshake)", n)
+	}
+}
+
+func TestSplitCompressed(t *testing.T) {
+	_, addr := startFakeServer(t, func(req []byte) [][]byte {
+		packets := split(0x80000001, single(infoPayload(0)), 16)
+		return packets[:1]
+	})
+	if _, err := dialTest(t, addr, time.Second).Info(); !errors.Is(err, ErrCompressed) {
+		t.Errorf("error = %v, want ErrCompressed", err)
+	}
+}
+
+func TestTruncatedPayload(t *testing.T) {
+	info := infoPayload(edfPort | edfSteamID | edfKeywords)[1:]
+	for _, n := range []int{0, 1, 5, 20, len(info) - 20, len(info) - 1} {
+		if _, err := parseInfo(info[:n]); err == nil {
+			t.Errorf("parseInfo accepted %d of %d bytes", n, len(info))
+		}
+	}
+	players := playersPayload()[1:]
+	for _, n := range []int{3, 8, len(players) - 1} {
+		if _, err := parsePlayers(players[:n]); err == nil {
+			t.Errorf("parsePlayers accepted %d of %d bytes", n, len(players))
+		}
+	}
+
+	// 通过网络收到截断的响应同样返回错误
+	_, addr := startFakeServer(t, func(req []byte) [][]byte {
+		return [][]byte{single(infoPayload(0)[:10])}
+	})
+	if _, err := dialTest(t, addr, time.Second).Info(); err == nil {
+		t.Error("Info accepted truncated response")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	_, addr := startFakeServer(t, func(req []byte) [][]byte { return nil })
+	start := time.Now()
+	_, err := dialTest(t, addr, 100*time.Millisecond).Info()
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout took %v", elapsed)
+	}
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/models"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+)
+
+// 清理确认令牌的有效期
+const cleanupTokenTTL = 5 * time.Minute
+
+// cleanupPlan 预览时生成、确认时执行的清理计划
+type cleanupPlan struct {
+	paths     []string
+	expiresAt time.Time
+}
+
+var (
+	cleanupPlans   = make(map[string]*cleanupPlan)
+	cleanupPlansMu sync.Mutex
+)
+
+// collectModReferences 收集 config.json、预设、模组包引用的模组及其依赖
+// 返回 模组ID -> 引用来源 列表
+func collectModReferences() map[string][]string {
+	refs := make(map[string][]string)
+	add := func(id, source string) {
+		id = strings.ToUpper(id)
+		for _, s := range refs[id] {
+			if s == source {
+				return
+			}
+		}
+		refs[id] = append(refs[id], source)
+	}
+
+	enabledMods, _ := loadEnabledMods()
+	for _, m := range enabledMods {
+		add(m.ModID, "config.json")
+	}
+
+	if entries, err := os.ReadDir(getPresetsDir()); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(getPresetsDir(), entry.Name()))
+			if err != nil {
+				continue
+			}
+			var preset models.ServerConfig
+			if err := json.Unmarshal(data, &preset); err != nil {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), ".json")
+			for _, m := range preset.Game.Mods {
+				add(m.ModID, "preset:"+name)
+			}
+		}
+	}
+
+	packs, _ := loadModpacks()
+	for _, pack := range packs {
+		for _, m := range pack.Mods {
+			add(m.ModID, "modpack:"+pack.Name)
+		}
+	}
+
+	// 被引用模组的依赖同样需要保留
+	libMods, _ := loadLibraryMods()
+	resolver := newModResolver(libMods)
+	referenced := make([]string, 0, len(refs))
+	for id := range refs {
+		referenced = append(referenced, id)
+	}
+	for _, id := range referenced {
+		closure, err := resolver.closure(id)
+		if err != nil {
+			continue
+		}
+		for _, dep := range closure {
+			if strings.ToUpper(dep) != id {
+				add(dep, "dependency:"+id)
+			}
+		}
+	}
+	return refs
+}
+
+// buildAddonDiskReport 统计 addons 目录中每个 addon 的占用和引用情况
+func buildAddonDiskReport() models.AddonDiskReport {
+	report := models.AddonDiskReport{Addons: []models.AddonUsage{}}
+
+	entries, err := os.ReadDir(getAddonsDir())
+	if err != nil {
+		return report
+	}
+
+	refs := collectModReferences()
+	enabledMods, _ := loadEnabledMods()
+	enabledSet := make(map[string]bool, len(enabledMods))
+	for _, m := range enabledMods {
+		enabledSet[strings.ToUpper(m.ModID)] = true
+	}
+	libMods, _ := loadLibraryMods()
+	libByID := make(map[string]models.Mod, len(libMods))
+	for _, m := range libMods {
+		libByID[strings.ToUpper(m.ID)] = m
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(getAddonsDir(), entry.Name())
+		usage := models.AddonUsage{AddonInfo: models.AddonInfo{Path: dir}}
+		if meta, err := readAddonMeta(dir); err == nil {
+			usage.HasMetadata = true
+			usage.ID = strings.ToUpper(meta.ID)
+			usage.Name = meta.Name
+			usage.Version = meta.version()
+		}
+		if !isValidModID(usage.ID) {
+			usage.ID = addonIDFromDir(entry.Name())
+		}
+		if lib, ok := libByID[usage.ID]; ok && usage.ID != "" {
+			if usage.Name == "" {
+				usage.Name = lib.Name
+			}
+			usage.LastEnabledAt = lib.LastEnabledAt
+		}
+		usage.Size, usage.LastModified = refreshAddonDiskUsage(dir)
+		usage.Enabled = enabledSet[usage.ID]
+		usage.ReferencedBy = refs[usage.ID]
+		if usage.ReferencedBy == nil {
+			usage.ReferencedBy = []string{}
+		}
+
+		report.TotalSize += usage.Size
+		if isUnusedAddon(usage) {
+			report.UnusedSize += usage.Size
+		}
+		report.Addons = append(report.Addons, usage)
+	}
+
+	sort.Slice(report.Addons, func(i, j int) bool {
+		return report.Addons[i].Size > report.Addons[j].Size
+	})
+	return report
+}
+
+// isUnusedAddon 可识别模组 ID 且没有任何引用的 addon 才视为未使用
+func isUnusedAddon(addon models.AddonUsage) bool {
+	return addon.ID != "" && len(addon.ReferencedBy) == 0
+}
+
+// GetAddonDiskReport 获取 addons 目录占用报告
+func GetAddonDiskReport(c *gin.Context) {
+	success(c, buildAddonDiskReport())
+}
+
+// newCleanupToken 生成随机确认令牌
+func newCleanupToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CleanupAddons 清理未被 config.json、预设或模组包引用的 addon
+// dry_run 为 true 时只返回待删除列表和确认令牌，携带令牌再次请求才会真正删除
+func CleanupAddons(c *gin.Context) {
+	var req struct {
+		DryRun bool   `json:"dry_run"`
+		Token  string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的请求数据")
+		return
+	}
+
+	report := buildAddonDiskReport()
+	unused := make(map[string]models.AddonUsage)
+	var candidates []models.AddonUsage
+	var totalSize int64
+	for _, addon := range report.Addons {
+		if isUnusedAddon(addon) {
+			unused[addon.Path] = addon
+			candidates = append(candidates, addon)
+			totalSize += addon.Size
+		}
+	}
+	if candidates == nil {
+		candidates = []models.AddonUsage{}
+	}
+
+	if req.DryRun || req.Token == "" {
+		token := ""
+		if len(candidates) > 0 {
+			plan := &cleanupPlan{expiresAt: time.Now().Add(cleanupTokenTTL)}
+			for _, addon := range candidates {
+				plan.paths = append(plan.paths, addon.Path)
+			}
+			token = newCleanupToken()
+			cleanupPlansMu.Lock()
+			for t, p := range cleanupPlans {
+				if time.Now().After(p.expiresAt) {
+					delete(cleanupPlans, t)
+				}
+			}
+			cleanupPlans[token] = plan
+			cleanupPlansMu.Unlock()
+		}
+		success(c, gin.H{
+			"dry_run":    true,
+			"candidates": candidates,
+			"total_size": totalSize,
+			"token":      token,
+		})
+		return
+	}
+
+	cleanupPlansMu.Lock()
+	plan, ok := cleanupPlans[req.Token]
+	delete(cleanupPlans, req.Token)
+	cleanupPlansMu.Unlock()
+	if !ok || time.Now().After(plan.expiresAt) {
+		fail(c, "确认令牌无效或已过期，请重新预览")
+		return
+	}
+
+	deleted := []string{}
+	skipped := []string{}
+	failed := map[string]string{}
+	var freed int64
+	addonsDir := getAddonsDir()
+	for _, path := range plan.paths {
+		// 预览之后被重新引用的 addon 不删除
+		addon, stillUnused := unused[path]
+		if !stillUnused {
+			skipped = append(skipped, path)
+			continue
+		}
+		if filepath.Dir(path) != addonsDir {
+			failed[path] = "路径不在 addons 目录内"
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			failed[path] = err.Error()
+			continue
+		}
+		deleted = append(deleted, path)
+		freed += addon.Size
+	}
+
+	if len(deleted) > 0 {
+		ws.Broadcast("已清理未使用的 addon: " + strings.Join(deleted, ", "))
+	}
+	success(c, gin.H{
+		"dry_run":    false,
+		"deleted":    deleted,
+		"skipped":    skipped,
+		"failed":     failed,
+		"freed_size": freed,
+	})
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+)
+
+// addon 目录中可能存放元数据的文件，按优先级排列
+var addonMetaFiles = []string{"ServerData.json", "meta"}
+
+// addonDependency addon 元数据中声明的依赖
+type addonDependency struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// addonMeta addon 元数据中 ARSM 关心的字段
+// 兼容顶层 version/dependencies 和 revision.version/dependencies 两种写法
+type addonMeta struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies []addonDependency `json:"dependencies"`
+	Revision     struct {
+		Version      string            `json:"version"`
+		Dependencies []addonDependency `json:"dependencies"`
+	} `json:"revision"`
+}
+
+func (m *addonMeta) version() string {
+	if m.Revision.Version != "" {
+		return m.Revision.Version
+	}
+	return m.Version
+}
+
+func (m *addonMeta) dependencies() []addonDependency {
+	if len(m.Revision.Dependencies) > 0 {
+		return m.Revision.Dependencies
+	}
+	return m.Dependencies
+}
+
+// getAddonsDir 获取 addons 目录
+func getAddonsDir() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "addons")
+}
+
+// addons 目录中 <Name>_<id> 形式目录的索引（大写 ID -> 目录名），addons 目录的修改时间变化时重建，
+// 避免每个模组都重新读取整个目录
+var (
+	addonIndexMu      sync.Mutex
+	addonIndexDir     string
+	addonIndexModTime time.Time
+	addonIndex        map[string]string
+)
+
+// addonDirIndex 返回 addonsDir 的目录索引，目录不存在时返回 nil
+func addonDirIndex(addonsDir string) map[string]string {
+	info, err := os.Stat(addonsDir)
+	if err != nil {
+		return nil
+	}
+	addonIndexMu.Lock()
+	defer addonIndexMu.Unlock()
+	if addonIndex != nil && addonIndexDir == addonsDir && addonIndexModTime.Equal(info.ModTime()) {
+		return addonIndex
+	}
+
+	entries, err := os.ReadDir(addonsDir)
+	if err != nil {
+		return nil
+	}
+	index := make(map[string]string)
+	for _, entry := range entries {
+		i := strings.LastIndex(entry.Name(), "_")
+		if !entry.IsDir() || i < 0 {
+			continue
+		}
+		// 同一 ID 有多个目录时与按名称排序后的第一个保持一致
+		if id := strings.ToUpper(entry.Name()[i+1:]); index[id] == "" {
+			index[id] = entry.Name()
+		}
+	}
+	addonIndex, addonIndexDir, addonIndexModTime = index, addonsDir, info.ModTime()
+	return index
+}
+
+// findAddonDir 查找模组对应的目录：addons/<id>，或游戏自动下载时使用的 addons/<Name>_<id>
+func findAddonDir(id string) string {
+	addonsDir := getAddonsDir()
+	modPath := filepath.Join(addonsDir, id)
+	if info, err := os.Stat(modPath); err == nil && info.IsDir() {
+		return modPath
+	}
+	if name := addonDirIndex(addonsDir)[strings.ToUpper(id)]; name != "" {
+		return filepath.Join(addonsDir, name)
+	}
+	return ""
+}
+
+// readAddonMeta 读取 addon 目录中的元数据
+func readAddonMeta(dir string) (*addonMeta, error) {
+	for _, name := range addonMetaFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var meta addonMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			continue
+		}
+		return &meta, nil
+	}
+	return nil, errors.New("未找到 addon 元数据")
+}
+
+// addonDiskUsage 统计目录大小和最后修改时间
+func addonDiskUsage(dir string) (int64, int64) {
+	var size, lastModified int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		if mt := info.ModTime().Unix(); mt > lastModified {
+			lastModified = mt
+		}
+		return nil
+	})
+	return size, lastModified
+}
+
+// addonUsage 缓存的目录占用，目录本身的修改时间变化时失效
+type addonUsage struct {
+	dirModTime   time.Time
+	size         int64
+	lastModified int64
+}
+
+// 由扫描填充的目录占用缓存，避免每次获取模组列表都遍历全部 addon 目录
+var (
+	addonUsageMu    sync.Mutex
+	addonUsageCache = make(map[string]addonUsage)
+)
+
+// refreshAddonDiskUsage 重新统计目录占用并写入缓存
+func refreshAddonDiskUsage(dir string) (int64, int64) {
+	size, lastModified := addonDiskUsage(dir)
+	if info, err := os.Stat(dir); err == nil {
+		addonUsageMu.Lock()
+		addonUsageCache[dir] = addonUsage{dirModTime: info.ModTime(), size: size, lastModified: lastModified}
+		addonUsageMu.Unlock()
+	}
+	return size, lastModified
+}
+
+// cachedAddonDiskUsage 读取缓存的目录占用，没有缓存或目录有变化时重新统计
+func cachedAddonDiskUsage(dir string) (int64, int64) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, 0
+	}
+	addonUsageMu.Lock()
+	usage, ok := addonUsageCache[dir]
+	addonUsageMu.Unlock()
+	if ok && usage.dirModTime.Equal(info.ModTime()) {
+		return usage.size, usage.lastModified
+	}
+	return refreshAddonDiskUsage(dir)
+}
+
+// inspectAddon 读取模组在磁盘上的状态，未下载时返回 nil；目录占用使用缓存
+func inspectAddon(id string) *models.AddonInfo {
+	dir := findAddonDir(id)
+	if dir == "" {
+		return nil
+	}
+
+	info := &models.AddonInfo{ID: id, Path: dir}
+	info.Size, info.LastModified = cachedAddonDiskUsage(dir)
+	if meta, err := readAddonMeta(dir); err == nil {
+		info.HasMetadata = true
+		info.Name = meta.Name
+		info.Version = meta.version()
+	}
+	return info
+}
+
+// modView 将磁盘状态合并到模组信息中
+func modView(mod models.Mod, pinned string) models.ModView {
+	view := models.ModView{Mod: mod, PinnedVersion: pinned}
+	addon := inspectAddon(mod.ID)
+	if addon == nil {
+		view.Downloaded = false
+		return view
+	}
+	view.Downloaded = true
+	view.InstalledVersion = addon.Version
+	view.Size = addon.Size
+	view.LastModified = addon.LastModified
+	view.VersionMismatch = pinned != "" && addon.Version != "" && pinned != addon.Version
+	return view
+}
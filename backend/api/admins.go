@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 服务端管理员以受信任玩家名单中 admin 为 true 的条目为准，
+// 由 applyRoster 写入 config.json 和使用共享管理员列表的预设
+
+// 使用共享管理员列表的预设文件
+func getSharedAdminPresetsPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_admin_presets.json")
+}
+
+func getPresetPath(name string) string {
+	return filepath.Join(getPresetsDir(), filepath.Base(name)+".json")
+}
+
+func loadSharedAdminPresets() []string {
+	data, err := os.ReadFile(getSharedAdminPresetsPath())
+	if err != nil {
+		return []string{}
+	}
+	var presets []string
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return []string{}
+	}
+	return presets
+}
+
+func saveSharedAdminPresets(presets []string) error {
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getSharedAdminPresetsPath(), data, 0644)
+}
+
+// validAdminID 是否为 Steam64 ID 或 Bohemia 身份 ID
+func validAdminID(id string) bool {
+	return reSteamID.MatchString(id) || reIdentityID.MatchString(id)
+}
+
+// loadConfigAdmins 读取 config.json 中的 game.admins
+func loadConfigAdmins() []string {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		return []string{}
+	}
+	var serverConfig models.ServerConfig
+	if err := json.Unmarshal(data, &serverConfig); err != nil {
+		return []string{}
+	}
+	return serverConfig.Game.Admins
+}
+
+// listServerAdmins 合并名单中的管理员和 config.json 中手动添加的管理员
+func listServerAdmins(roster []models.RosterEntry, configAdmins []string, now int64) []models.ServerAdmin {
+	admins := []models.ServerAdmin{}
+	inRoster := make(map[string]bool)
+	for _, e := range roster {
+		inRoster[e.Identity] = true
+		if e.Admin {
+			admins = append(admins, models.ServerAdmin{
+				ID:        e.Identity,
+				Name:      e.Name,
+				Note:      e.Note,
+				ExpiresAt: e.ExpiresAt,
+				Expired:   rosterExpired(e, now),
+				Managed:   true,
+			})
+		}
+	}
+	seen := make(map[string]bool)
+	for _, a := range configAdmins {
+		key := strings.ToLower(strings.TrimSpace(a))
+		if key == "" || inRoster[key] {
+			continue
+		}
+		admin := models.ServerAdmin{ID: a, Invalid: !validAdminID(strings.TrimSpace(a)), Duplicate: seen[key]}
+		seen[key] = true
+		admins = append(admins, admin)
+	}
+	return admins
+}
+
+// GetServerAdmins 获取服务端管理员
+func GetServerAdmins(c *gin.Context) {
+	success(c, listServerAdmins(loadRoster(), loadConfigAdmins(), time.Now().Unix()))
+}
+
+// AddServerAdmin 添加管理员（仅管理员）；已在名单中的玩家设为管理员，config.json 中手动添加的改为由名单管理
+func AddServerAdmin(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var req struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		Note      string `json:"note"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的管理员数据")
+		return
+	}
+	e := models.RosterEntry{Identity: req.ID, Name: req.Name, Note: req.Note, Admin: true, ExpiresAt: req.ExpiresAt}
+	if err := validateRosterEntry(&e); err != nil {
+		fail(c, err.Error())
+		return
+	}
+
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	if i := findRosterEntry(roster, e.Identity); i >= 0 {
+		existing := roster[i]
+		if existing.Admin && !rosterExpired(existing, time.Now().Unix()) {
+			fail(c, fmt.Sprintf("%s 已是管理员", e.Identity))
+			return
+		}
+		if e.Name == "" {
+			e.Name = existing.Name
+		}
+		if e.Note == "" {
+			e.Note = existing.Note
+		}
+		e.AddedBy, e.AddedAt = existing.AddedBy, existing.AddedAt
+		roster[i] = e
+	} else {
+		e.AddedBy, e.AddedAt = currentUsername(c), time.Now().Unix()
+		roster = append(roster, e)
+	}
+	if err := saveAndApplyRoster(roster); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, listServerAdmins([]models.RosterEntry{e}, nil, time.Now().Unix())[0])
+}
+
+// RemoveServerAdmin 移除管理员（仅管理员）；名单中的玩家保留为受信任玩家，手动添加的直接从配置中删除
+func RemoveServerAdmin(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	if i := findRosterEntry(roster, id); i >= 0 && roster[i].Admin {
+		roster[i].Admin = false
+		if err := saveAndApplyRoster(roster); err != nil {
+			fail(c, "保存失败: "+err.Error())
+			return
+		}
+		success(c, nil)
+		return
+	}
+
+	found := false
+	for _, a := range loadConfigAdmins() {
+		if strings.EqualFold(strings.TrimSpace(a), id) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		fail(c, "管理员不存在")
+		return
+	}
+	if _, err := applyRoster(id); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// GetSharedAdminPresets 获取使用共享管理员列表的预设
+func GetSharedAdminPresets(c *gin.Context) {
+	success(c, loadSharedAdminPresets())
+}
+
+// SaveSharedAdminPresets 设置使用共享管理员列表的预设（仅管理员），保存后立即写入这些预设
+func SaveSharedAdminPresets(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var req struct {
+		Presets []string `json:"presets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的请求数据")
+		return
+	}
+	presets := []string{}
+	seen := make(map[string]bool)
+	for _, name := range req.Presets {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if name != filepath.Base(name) {
+			fail(c, "无效的预设名称: "+name)
+			return
+		}
+		if _, err := os.Stat(getPresetPath(name)); err != nil {
+			fail(c, "预设不存在: "+name)
+			return
+		}
+		seen[name] = true
+		presets = append(presets, name)
+	}
+	if err := saveSharedAdminPresets(presets); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	if _, err := applyRoster(); err != nil {
+		fail(c, "已保存，但写入配置失败: "+err.Error())
+		return
+	}
+	success(c, presets)
+}
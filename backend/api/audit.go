@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"arsm/auth"
+	"arsm/models"
+	"arsm/store"
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+const bucketAudit = "audit" // 序号 -> AuditEntry
+
+// 审计记录的保留上限：被拒绝的命令等非管理员操作也会写入，超过条数或时间的最早记录在写入新记录时删除
+const (
+	maxAuditEntries = 50000
+	auditRetention  = 365 * 24 * time.Hour
+)
+
+// recordAudit 追加一条审计记录，写入失败只输出日志
+func recordAudit(entry models.AuditEntry) {
+	if entry.Time == 0 {
+		entry.Time = time.Now().Unix()
+	}
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketAudit))
+		if err != nil {
+			return err
+		}
+		if entry.ID, err = b.NextSequence(); err != nil {
+			return err
+		}
+		if err := store.PutJSON(b, store.Itob(entry.ID), entry); err != nil {
+			return err
+		}
+		return pruneLogBucket(b, entry.ID, entry.Time, maxAuditEntries, auditRetention)
+	})
+	if err != nil {
+		fmt.Printf("[ARSM] 写入审计记录失败: %v\n", err)
+	}
+}
+
+// currentRole 当前用户角色（未启用认证时视为管理员）
+func currentRole(c *gin.Context) string {
+	if isAdmin(c) {
+		return "admin"
+	}
+	_, role, _ := auth.GetCurrentUser(c)
+	return role
+}
+
+// GetAuditLog 获取审计记录，从新到旧；可按 action 前缀、user 过滤，before 为上一页最后一条的 ID
+func GetAuditLog(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	action := c.Query("action")
+	user := c.Query("user")
+	before, _ := strconv.ParseUint(c.Query("before"), 10, 64)
+	limit := queryInt(c, "limit", 50, 500)
+
+	entries := []models.AuditEntry{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketAudit))
+		if b == nil {
+			return nil
+		}
+		cur := b.Cursor()
+		k, v := cur.Last()
+		if before > 0 {
+			if sk, _ := cur.Seek(store.Itob(before)); sk != nil {
+				k, v = cur.Prev()
+			}
+		}
+		for ; k != nil && len(entries) < limit; k, v = cur.Prev() {
+			var e models.AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if action != "" && !strings.HasPrefix(e.Action, action) {
+				continue
+			}
+			if user != "" && e.User != user {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		fail(c, "读取审计记录失败: "+err.Error())
+		return
+	}
+	success(c, entries)
+}
@@ -0,0 +1,568 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/models"
+	"arsm/store"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+	"github.com/multiplay/go-battleye"
+	bolt "go.etcd.io/bbolt"
+)
+
+const bucketBans = "bans" // 封禁序号 -> Ban
+
+// 封禁状态
+const (
+	banActive  = "active"
+	banExpired = "expired"
+	banRevoked = "revoked"
+)
+
+const defaultBanReason = "Banned by Admin"
+
+// 同步与下发封禁时串行执行，避免服务端封禁列表的序号在操作中途变化
+var banMu sync.Mutex
+
+// serverBan 服务端 bans 命令输出中的一条
+type serverBan struct {
+	Index       int
+	Identity    string // GUID 或 IP
+	MinutesLeft int    // -1 表示永久
+	Reason      string
+}
+
+// bans 输出中的一行：[#] [GUID/IP] [Minutes left] [Reason]
+var reServerBan = regexp.MustCompile(`^(\d+)\s+(\S+)\s+(perm|-?\d+)\s*(.*)$`)
+
+func init() {
+	// 服务端重启后 RCON 会重新连接，此时重新下发 ARSM 管理的封禁
+	rconManager.OnConnect(func(*battleye.Client) {
+		go func() {
+			result, err := syncBans(rconSystemUser)
+			if err != nil {
+				return
+			}
+			if len(result.Reapplied) > 0 {
+				ws.Broadcast(fmt.Sprintf("[封禁] 已重新下发 %d 条封禁", len(result.Reapplied)))
+			}
+		}()
+	})
+}
+
+// banStatus 计算封禁当前状态
+func banStatus(ban models.Ban, now int64) string {
+	switch {
+	case ban.RevokedAt > 0:
+		return banRevoked
+	case ban.ExpiresAt > 0 && ban.ExpiresAt <= now:
+		return banExpired
+	default:
+		return banActive
+	}
+}
+
+// remainingMinutes 封禁剩余分钟数，0 表示永久
+func remainingMinutes(ban models.Ban, now int64) int {
+	if ban.ExpiresAt == 0 {
+		return 0
+	}
+	minutes := int((ban.ExpiresAt - now + 59) / 60)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
+
+// sanitizeBanReason 原因中不能有换行（会截断 RCON 命令），为空时使用默认原因
+func sanitizeBanReason(reason string) string {
+	reason = strings.Join(strings.Fields(reason), " ")
+	if reason == "" {
+		return defaultBanReason
+	}
+	return reason
+}
+
+// loadBans 读取全部封禁记录，按创建时间倒序
+func loadBans() ([]models.Ban, error) {
+	bans := []models.Ban{}
+	now := time.Now().Unix()
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketBans))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var ban models.Ban
+			if err := json.Unmarshal(v, &ban); err != nil {
+				return err
+			}
+			ban.Status = banStatus(ban, now)
+			bans = append(bans, ban)
+			return nil
+		})
+	})
+	sort.Slice(bans, func(i, j int) bool { return bans[i].ID > bans[j].ID })
+	return bans, err
+}
+
+// saveBan 写入封禁记录，新记录分配序号
+func saveBan(ban *models.Ban) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketBans))
+		if err != nil {
+			return err
+		}
+		if ban.ID == 0 {
+			if ban.ID, err = b.NextSequence(); err != nil {
+				return err
+			}
+		}
+		ban.Status = ""
+		err = store.PutJSON(b, store.Itob(ban.ID), ban)
+		ban.Status = banStatus(*ban, time.Now().Unix())
+		return err
+	})
+}
+
+// getBan 按序号读取封禁记录
+func getBan(id uint64) (models.Ban, bool, error) {
+	var ban models.Ban
+	var found bool
+	err := store.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = store.GetJSON(tx.Bucket([]byte(bucketBans)), store.Itob(id), &ban)
+		return err
+	})
+	ban.Status = banStatus(ban, time.Now().Unix())
+	return ban, found, err
+}
+
+// banServerKey 服务端封禁列表中对应的标识：按会话编号封禁时服务端记录的 GUID 可能与 Identity 不同
+func banServerKey(ban models.Ban) string {
+	if ban.ServerID != "" {
+		return ban.ServerID
+	}
+	return ban.Identity
+}
+
+// banMatches 封禁记录是否对应该标识（Identity 或服务端标识）
+func banMatches(ban models.Ban, identity string) bool {
+	return (ban.Identity != "" && strings.EqualFold(ban.Identity, identity)) ||
+		(ban.ServerID != "" && strings.EqualFold(ban.ServerID, identity))
+}
+
+// latestBanFor 返回某标识最近的一条封禁记录（包括已过期、已解除的）
+func latestBanFor(bans []models.Ban, identity string) (models.Ban, bool) {
+	for _, ban := range bans { // 已按序号倒序
+		if banMatches(ban, identity) {
+			return ban, true
+		}
+	}
+	return models.Ban{}, false
+}
+
+// newServerBanID 封禁后服务端列表中新增的标识，优先 GUID；找不到时返回空
+func newServerBanID(before, after []serverBan) string {
+	seen := make(map[string]bool, len(before))
+	for _, sb := range before {
+		seen[strings.ToLower(sb.Identity)] = true
+	}
+	ip := ""
+	for _, sb := range after {
+		id := strings.ToLower(sb.Identity)
+		if seen[id] {
+			continue
+		}
+		if net.ParseIP(sb.Identity) == nil {
+			return id
+		}
+		if ip == "" {
+			ip = id
+		}
+	}
+	return ip
+}
+
+// parseServerBans 解析 bans 命令输出（GUID 封禁与 IP 封禁两张表）
+func parseServerBans(output string) []serverBan {
+	var bans []serverBan
+	for _, line := range strings.Split(output, "\n") {
+		m := reServerBan.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(m[1])
+		minutes := -1
+		if m[3] != "perm" {
+			minutes, _ = strconv.Atoi(m[3])
+		}
+		bans = append(bans, serverBan{
+			Index:       index,
+			Identity:    m[2],
+			MinutesLeft: minutes,
+			Reason:      strings.TrimSpace(m[4]),
+		})
+	}
+	return bans
+}
+
+// fetchServerBans 读取服务端当前的封禁列表
+func fetchServerBans() ([]serverBan, error) {
+	resp, err := rconExec("bans")
+	if err != nil {
+		return nil, err
+	}
+	return parseServerBans(resp), nil
+}
+
+// findOnlinePlayer 按会话编号查找在线玩家
+func findOnlinePlayer(id string) (models.Player, bool) {
+	resp, err := rconExec("players")
+	if err != nil {
+		return models.Player{}, false
+	}
+	players := parsePlayerList(resp)
+	playerSessions.observe(players)
+	for _, p := range players {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return models.Player{}, false
+}
+
+// banRequest 封禁请求：按在线玩家的会话编号，或按 GUID / 身份 ID（可离线）
+type banRequest struct {
+	PlayerID string `json:"player_id"`
+	Identity string `json:"identity"`
+	Name     string `json:"name"`
+	Duration *int   `json:"duration"` // 分钟，0 表示永久；缺省时使用模板时长或永久
+	Reason   string `json:"reason"`
+	Template string `json:"template"` // 原因模板名称，reason 为空时使用
+}
+
+// issueBan 下发封禁并记录；按标识封禁时 RCON 不可用也会保存，下次连接时自动下发。
+// check 不为空时在下发前检查命令权限
+func issueBan(req banRequest, issuer string, check func(string) error) (models.Ban, bool, error) {
+	reason, duration, err := resolveReason(req.Reason, req.Template, "ban")
+	if err != nil {
+		return models.Ban{}, false, err
+	}
+	if req.Duration != nil {
+		duration = *req.Duration
+	}
+	if duration < 0 {
+		return models.Ban{}, false, errors.New("封禁时长不能为负数")
+	}
+	if req.PlayerID = strings.TrimSpace(req.PlayerID); req.PlayerID != "" {
+		if req.PlayerID, err = normalizePlayerID(req.PlayerID); err != nil {
+			return models.Ban{}, false, err
+		}
+	}
+	req.Identity = strings.TrimSpace(req.Identity)
+	if req.PlayerID == "" && req.Identity == "" {
+		return models.Ban{}, false, errors.New("请提供 player_id 或 identity")
+	}
+
+	now := time.Now().Unix()
+	ban := models.Ban{
+		Identity:  strings.ToLower(req.Identity),
+		Name:      strings.TrimSpace(req.Name),
+		Reason:    sanitizeBanReason(reason),
+		Duration:  duration,
+		CreatedAt: now,
+		IssuedBy:  issuer,
+		Source:    "arsm",
+	}
+	if duration > 0 {
+		ban.ExpiresAt = now + int64(duration)*60
+	}
+
+	var command string
+	var serverBansBefore []serverBan
+	if req.PlayerID != "" {
+		player, ok := findOnlinePlayer(req.PlayerID)
+		if !ok {
+			return models.Ban{}, false, fmt.Errorf("玩家 #%s 不在线", req.PlayerID)
+		}
+		ban.Identity = playerIdentity(player)
+		ban.Name, ban.IP = player.Name, player.IP
+		command = fmt.Sprintf("ban %s %d %s", req.PlayerID, duration, ban.Reason)
+		// 服务端按 BattlEye GUID 记录封禁，身份 ID / 平台 ID 不会出现在 bans 列表中
+		if player.GUID == "" {
+			serverBansBefore, _ = fetchServerBans()
+		}
+	} else {
+		command = fmt.Sprintf("addBan %s %d %s", ban.Identity, duration, ban.Reason)
+	}
+	if check != nil {
+		if err := check(command); err != nil {
+			return models.Ban{}, false, err
+		}
+	}
+
+	banMu.Lock()
+	defer banMu.Unlock()
+
+	if ban.Identity != "" {
+		bans, err := loadBans()
+		if err != nil {
+			return models.Ban{}, false, err
+		}
+		if existing, ok := latestBanFor(bans, ban.Identity); ok && existing.Status == banActive {
+			return models.Ban{}, false, fmt.Errorf("该玩家已被封禁（记录 #%d）", existing.ID)
+		}
+	}
+
+	_, err = rconExecAs(issuer, command)
+	if err != nil && req.PlayerID != "" {
+		return models.Ban{}, false, err
+	}
+	applied := err == nil
+	if serverBansBefore != nil {
+		if after, err := fetchServerBans(); err == nil {
+			if id := newServerBanID(serverBansBefore, after); id != "" && id != ban.Identity {
+				ban.ServerID = id
+			}
+		}
+	}
+	if err := saveBan(&ban); err != nil {
+		return ban, applied, err
+	}
+	return ban, applied, nil
+}
+
+// revokeBan 解除封禁，并尝试从服务端封禁列表中移除
+func revokeBan(id uint64, by string) (models.Ban, bool, error) {
+	banMu.Lock()
+	defer banMu.Unlock()
+
+	ban, found, err := getBan(id)
+	if err != nil {
+		return ban, false, err
+	}
+	if !found {
+		return ban, false, errors.New("封禁记录不存在")
+	}
+	if ban.Status == banRevoked {
+		return ban, false, errors.New("该封禁已解除")
+	}
+	ban.RevokedAt = time.Now().Unix()
+	ban.RevokedBy = by
+	if err := saveBan(&ban); err != nil {
+		return ban, false, err
+	}
+
+	// 服务端移除失败时，下次同步会再次尝试
+	removed := false
+	if banServerKey(ban) != "" || ban.IP != "" {
+		if serverBans, err := fetchServerBans(); err == nil {
+			removed = removeServerBans(by, serverBans, func(sb serverBan) bool {
+				return banMatches(ban, sb.Identity) || (banServerKey(ban) == "" && sb.Identity == ban.IP)
+			}) > 0
+		}
+	}
+	return ban, removed, nil
+}
+
+// removeServerBans 按序号从大到小移除匹配的服务端封禁（移除会使后面的序号前移）
+func removeServerBans(user string, serverBans []serverBan, match func(serverBan) bool) int {
+	var indexes []int
+	for _, sb := range serverBans {
+		if match(sb) {
+			indexes = append(indexes, sb.Index)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	removed := 0
+	for _, index := range indexes {
+		command := "removeBan " + strconv.Itoa(index)
+		if _, err := rconExecAs(user, command); err == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// syncBans 与服务端封禁列表对齐：重新下发缺失的 ARSM 封禁，导入服务端独有的封禁，
+// 移除已在 ARSM 解封或已过期的封禁（剩余时长向上取整，服务端可能仍列出 ARSM 认为已过期的封禁）
+func syncBans(user string) (models.BanSyncResult, error) {
+	banMu.Lock()
+	defer banMu.Unlock()
+
+	result := models.BanSyncResult{Reapplied: []uint64{}, Imported: []uint64{}, Removed: []string{}}
+	serverBans, err := fetchServerBans()
+	if err != nil {
+		return result, err
+	}
+	bans, err := loadBans()
+	if err != nil {
+		return result, err
+	}
+	now := time.Now().Unix()
+
+	onServer := make(map[string]bool)
+	for _, sb := range serverBans {
+		onServer[strings.ToLower(sb.Identity)] = true
+	}
+
+	// 服务端缺失的有效封禁：按剩余时长重新下发
+	reapplied := make(map[string]bool)
+	for _, ban := range bans {
+		key := banServerKey(ban)
+		if ban.Status != banActive || key == "" || onServer[key] || reapplied[key] {
+			continue
+		}
+		command := fmt.Sprintf("addBan %s %d %s", key, remainingMinutes(ban, now), ban.Reason)
+		if _, err := rconExecAs(user, command); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("#%d: %v", ban.ID, err))
+			continue
+		}
+		reapplied[key] = true
+		result.Reapplied = append(result.Reapplied, ban.ID)
+	}
+
+	// 服务端独有的封禁导入为 server 来源；ARSM 已解封或已过期的从服务端移除
+	revoked := make(map[string]bool)
+	for _, sb := range serverBans {
+		identity := strings.ToLower(sb.Identity)
+		latest, known := latestBanFor(bans, identity)
+		if !known && net.ParseIP(sb.Identity) != nil {
+			latest, known = latestBanForIP(bans, sb.Identity)
+		}
+		switch {
+		case known && latest.Status == banRevoked:
+			revoked[identity] = true
+		case known && latest.Status == banExpired && sb.MinutesLeft >= 0:
+			// 限时封禁的残留，服务端上的永久封禁不可能来自已过期的记录，按服务端独有封禁导入
+			revoked[identity] = true
+		case known && latest.Status == banActive:
+			// 已由 ARSM 管理
+		default:
+			ban := models.Ban{
+				Reason:    sanitizeBanReason(sb.Reason),
+				CreatedAt: now,
+				IssuedBy:  "server",
+				Source:    "server",
+			}
+			if net.ParseIP(sb.Identity) != nil {
+				ban.IP = sb.Identity
+			} else {
+				ban.Identity = identity
+			}
+			if sb.MinutesLeft >= 0 {
+				ban.Duration = sb.MinutesLeft
+				ban.ExpiresAt = now + int64(sb.MinutesLeft)*60
+			}
+			if err := saveBan(&ban); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+				continue
+			}
+			bans = append([]models.Ban{ban}, bans...)
+			result.Imported = append(result.Imported, ban.ID)
+		}
+	}
+	if len(revoked) > 0 {
+		removeServerBans(user, serverBans, func(sb serverBan) bool { return revoked[strings.ToLower(sb.Identity)] })
+		for identity := range revoked {
+			result.Removed = append(result.Removed, identity)
+		}
+		sort.Strings(result.Removed)
+	}
+	return result, nil
+}
+
+// latestBanForIP 返回某 IP 最近的一条无标识封禁记录
+func latestBanForIP(bans []models.Ban, ip string) (models.Ban, bool) {
+	for _, ban := range bans {
+		if ban.Identity == "" && ban.IP == ip {
+			return ban, true
+		}
+	}
+	return models.Ban{}, false
+}
+
+// GetBans 获取封禁列表，status 可为 active / expired / revoked，q 匹配标识、名称、原因
+func GetBans(c *gin.Context) {
+	bans, err := loadBans()
+	if err != nil {
+		fail(c, "读取封禁列表失败: "+err.Error())
+		return
+	}
+	status := c.Query("status")
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	admin := isAdmin(c)
+
+	result := []models.Ban{}
+	for _, ban := range bans {
+		if status != "" && ban.Status != status {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(ban.Identity+" "+ban.Name+" "+ban.Reason), q) {
+			continue
+		}
+		if !admin {
+			ban.IP = maskIP(ban.IP)
+		}
+		result = append(result, ban)
+	}
+	success(c, result)
+}
+
+// CreateBan 封禁玩家（在线玩家按会话编号，离线玩家按 GUID / 身份 ID）
+func CreateBan(c *gin.Context) {
+	var req banRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的请求数据")
+		return
+	}
+	ban, applied, err := issueBan(req, currentUsername(c), rconPolicyChecker(c))
+	if err != nil {
+		failRCON(c, "封禁失败: ", err)
+		return
+	}
+	success(c, gin.H{"ban": ban, "applied": applied})
+}
+
+// DeleteBan 解除封禁
+func DeleteBan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		fail(c, "无效的封禁 ID")
+		return
+	}
+	if !authorizeRCON(c, "removeBan") {
+		return
+	}
+	ban, removed, err := revokeBan(id, currentUsername(c))
+	if err != nil {
+		fail(c, "解封失败: "+err.Error())
+		return
+	}
+	success(c, gin.H{"ban": ban, "removed_from_server": removed})
+}
+
+// SyncBans 手动与服务端封禁列表同步
+func SyncBans(c *gin.Context) {
+	// 同步可能重新下发或移除封禁
+	if !authorizeRCON(c, "bans", "addBan", "removeBan") {
+		return
+	}
+	result, err := syncBans(currentUsername(c))
+	if err != nil {
+		fail(c, "同步封禁列表失败: "+err.Error())
+		return
+	}
+	success(c, result)
+}
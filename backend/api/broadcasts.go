@@ -0,0 +1,372 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 广播暂停原因
+const (
+	broadcastPausedDisabled   = "disabled"
+	broadcastPausedHours      = "inactive_hours"
+	broadcastPausedServerDown = "server_down"
+	broadcastPausedEmpty      = "empty"
+)
+
+const broadcastCheckInterval = 30 * time.Second
+
+// broadcastState 消息组的运行状态，ARSM 重启后从第一条重新开始
+type broadcastState struct {
+	lastSent time.Time
+	next     int
+}
+
+var (
+	broadcastMu     sync.Mutex
+	broadcastStates = make(map[string]*broadcastState)
+)
+
+// 消息组文件的读-改-写需持有该锁，避免并发修改时丢失改动
+var broadcastSetsMu sync.Mutex
+
+// 广播消息组文件
+func getBroadcastsPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_broadcasts.json")
+}
+
+func loadBroadcastSets() ([]models.BroadcastSet, error) {
+	data, err := os.ReadFile(getBroadcastsPath())
+	if err != nil {
+		return []models.BroadcastSet{}, nil
+	}
+	var sets []models.BroadcastSet
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return []models.BroadcastSet{}, nil
+	}
+	return sets, nil
+}
+
+func saveBroadcastSets(sets []models.BroadcastSet) error {
+	// 运行状态不写入文件
+	stored := make([]models.BroadcastSet, len(sets))
+	for i, set := range sets {
+		set.LastSentAt, set.NextIndex, set.PauseReason = 0, 0, ""
+		stored[i] = set
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getBroadcastsPath(), data, 0644)
+}
+
+func findBroadcastSet(sets []models.BroadcastSet, name string) int {
+	for i := range sets {
+		if sets[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inActiveHours 当前是否在消息组的生效时段内
+func inActiveHours(set models.BroadcastSet, now time.Time) bool {
+	if set.ActiveFrom == "" && set.ActiveTo == "" {
+		return true
+	}
+	from, err1 := parseClock(set.ActiveFrom)
+	to, err2 := parseClock(set.ActiveTo)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	// 跨越午夜，如 22:00 - 02:00
+	return minute >= from || minute < to
+}
+
+// broadcastPauseReason 消息组当前不发送的原因，为空表示正常发送
+func broadcastPauseReason(set models.BroadcastSet, now time.Time) string {
+	switch {
+	case !set.Enabled:
+		return broadcastPausedDisabled
+	case !inActiveHours(set, now):
+		return broadcastPausedHours
+	case !rconManager.Connected():
+		return broadcastPausedServerDown
+	case playerSessions.count() == 0:
+		return broadcastPausedEmpty
+	}
+	return ""
+}
+
+// withBroadcastState 填充运行状态
+func withBroadcastState(set models.BroadcastSet, now time.Time) models.BroadcastSet {
+	broadcastMu.Lock()
+	if st := broadcastStates[set.Name]; st != nil {
+		if !st.lastSent.IsZero() {
+			set.LastSentAt = st.lastSent.Unix()
+		}
+		set.NextIndex = st.next
+	}
+	broadcastMu.Unlock()
+	if len(set.Messages) > 0 {
+		set.NextIndex %= len(set.Messages)
+	}
+	set.PauseReason = broadcastPauseReason(set, now)
+	return set
+}
+
+// sendNextBroadcast 以指定用户身份发送消息组中的下一条消息；check 不为空时在下发前检查实际命令的权限
+func sendNextBroadcast(user string, set models.BroadcastSet, now time.Time, check func(string) error) error {
+	if len(set.Messages) == 0 {
+		return fmt.Errorf("消息组没有消息")
+	}
+	broadcastMu.Lock()
+	st := broadcastStates[set.Name]
+	if st == nil {
+		st = &broadcastState{}
+		broadcastStates[set.Name] = st
+	}
+	index := st.next % len(set.Messages)
+	broadcastMu.Unlock()
+
+	command, err := messageCommand("-1", set.Messages[index])
+	if err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check(command); err != nil {
+			return err
+		}
+	}
+	if err := execPlayerCommand(user, command); err != nil {
+		return err
+	}
+
+	broadcastMu.Lock()
+	st.lastSent = now
+	st.next = (index + 1) % len(set.Messages)
+	broadcastMu.Unlock()
+	return nil
+}
+
+// runBroadcasts 发送所有到期的消息组
+func runBroadcasts(now time.Time) {
+	sets, _ := loadBroadcastSets()
+	for _, set := range sets {
+		if broadcastPauseReason(set, now) != "" || len(set.Messages) == 0 {
+			continue
+		}
+		broadcastMu.Lock()
+		st := broadcastStates[set.Name]
+		due := st == nil || now.Sub(st.lastSent) >= time.Duration(set.Interval)*time.Minute
+		broadcastMu.Unlock()
+		if due {
+			sendNextBroadcast(rconSystemUser, set, now, nil)
+		}
+	}
+}
+
+// StartBroadcastScheduler 定时发送轮播消息；服务端未运行或无人在线时自动暂停
+func StartBroadcastScheduler() {
+	go func() {
+		for {
+			time.Sleep(broadcastCheckInterval)
+			runBroadcasts(time.Now())
+		}
+	}()
+}
+
+// validateBroadcastSet 检查并规范化消息组
+func validateBroadcastSet(set *models.BroadcastSet) error {
+	set.Name = strings.TrimSpace(set.Name)
+	if set.Name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	messages := make([]string, 0, len(set.Messages))
+	for _, m := range set.Messages {
+		// 换行会截断 RCON 命令
+		if m = strings.Join(strings.Fields(m), " "); m != "" {
+			messages = append(messages, m)
+		}
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("至少需要一条消息")
+	}
+	set.Messages = messages
+	if set.Interval < 1 {
+		return fmt.Errorf("发送间隔至少为 1 分钟")
+	}
+	if (set.ActiveFrom == "") != (set.ActiveTo == "") {
+		return fmt.Errorf("生效时段需要同时设置开始和结束时间")
+	}
+	if set.ActiveFrom != "" {
+		if _, err := parseClock(set.ActiveFrom); err != nil {
+			return err
+		}
+		if _, err := parseClock(set.ActiveTo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorizeBroadcastSet 按定时发送时实际下发的命令检查当前用户的 RCON 权限，
+// 消息组由 system 用户发送，不能绕过角色对 say 的限制
+func authorizeBroadcastSet(c *gin.Context, set models.BroadcastSet) bool {
+	commands := make([]string, 0, len(set.Messages))
+	for _, m := range set.Messages {
+		if command, err := messageCommand("-1", m); err == nil {
+			commands = append(commands, command)
+		}
+	}
+	return authorizeRCON(c, commands...)
+}
+
+// GetBroadcastSets 获取广播消息组及其运行状态
+func GetBroadcastSets(c *gin.Context) {
+	sets, _ := loadBroadcastSets()
+	now := time.Now()
+	for i := range sets {
+		sets[i] = withBroadcastState(sets[i], now)
+	}
+	success(c, sets)
+}
+
+// CreateBroadcastSet 新建广播消息组
+func CreateBroadcastSet(c *gin.Context) {
+	var set models.BroadcastSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		fail(c, "无效的消息组数据")
+		return
+	}
+	if err := validateBroadcastSet(&set); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	if !authorizeBroadcastSet(c, set) {
+		return
+	}
+	broadcastSetsMu.Lock()
+	defer broadcastSetsMu.Unlock()
+	sets, _ := loadBroadcastSets()
+	if findBroadcastSet(sets, set.Name) >= 0 {
+		fail(c, "消息组名称已存在")
+		return
+	}
+	sets = append(sets, set)
+	if err := saveBroadcastSets(sets); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, withBroadcastState(set, time.Now()))
+}
+
+// UpdateBroadcastSet 修改广播消息组（可改名、启用 / 停用）
+func UpdateBroadcastSet(c *gin.Context) {
+	var set models.BroadcastSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		fail(c, "无效的消息组数据")
+		return
+	}
+	name := c.Param("name")
+	if set.Name == "" {
+		set.Name = name
+	}
+	if err := validateBroadcastSet(&set); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	if !authorizeBroadcastSet(c, set) {
+		return
+	}
+	broadcastSetsMu.Lock()
+	defer broadcastSetsMu.Unlock()
+	sets, _ := loadBroadcastSets()
+	i := findBroadcastSet(sets, name)
+	if i < 0 {
+		fail(c, "消息组不存在")
+		return
+	}
+	if set.Name != name && findBroadcastSet(sets, set.Name) >= 0 {
+		fail(c, "消息组名称已存在")
+		return
+	}
+	sets[i] = set
+	if err := saveBroadcastSets(sets); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+
+	broadcastMu.Lock()
+	if st := broadcastStates[name]; st != nil && set.Name != name {
+		delete(broadcastStates, name)
+		broadcastStates[set.Name] = st
+	}
+	broadcastMu.Unlock()
+	success(c, withBroadcastState(set, time.Now()))
+}
+
+// DeleteBroadcastSet 删除广播消息组
+func DeleteBroadcastSet(c *gin.Context) {
+	name := c.Param("name")
+	broadcastSetsMu.Lock()
+	defer broadcastSetsMu.Unlock()
+	sets, _ := loadBroadcastSets()
+	i := findBroadcastSet(sets, name)
+	if i < 0 {
+		fail(c, "消息组不存在")
+		return
+	}
+	if !authorizeBroadcastSet(c, sets[i]) {
+		return
+	}
+	sets = append(sets[:i], sets[i+1:]...)
+	if err := saveBroadcastSets(sets); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+
+	broadcastMu.Lock()
+	delete(broadcastStates, name)
+	broadcastMu.Unlock()
+	success(c, nil)
+}
+
+// SendBroadcastNow 立即发送消息组的下一条消息（不受启用状态和时段限制）
+func SendBroadcastNow(c *gin.Context) {
+	sets, _ := loadBroadcastSets()
+	i := findBroadcastSet(sets, c.Param("name"))
+	if i < 0 {
+		fail(c, "消息组不存在")
+		return
+	}
+	now := time.Now()
+	if err := sendNextBroadcast(currentUsername(c), sets[i], now, rconPolicyChecker(c)); err != nil {
+		failRCON(c, "发送失败: ", err)
+		return
+	}
+	success(c, withBroadcastState(sets[i], now))
+}
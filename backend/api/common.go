@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"time"
 
+	"arsm/auth"
 	"arsm/config"
 	"arsm/models"
 
@@ -31,6 +32,56 @@ func fail(c *gin.Context, message string) {
 	c.JSON(http.StatusOK, Response{Code: 1, Message: message})
 }
 
+// failWithData 失败响应，同时返回部分结果（如多步骤操作的执行情况）
+func failWithData(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{Code: 1, Message: message, Data: data})
+}
+
+// isAdmin 当前用户是否为管理员（未启用认证时视为管理员）
+func isAdmin(c *gin.Context) bool {
+	if !auth.GetUserManager().IsEnabled() {
+		return true
+	}
+	_, role, ok := auth.GetCurrentUser(c)
+	return ok && role == "admin"
+}
+
+// requireAdmin 非管理员时返回 403，调用方应直接返回
+func requireAdmin(c *gin.Context) bool {
+	if isAdmin(c) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, Response{Code: 403, Message: "无权限访问"})
+	return false
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，避免写入中途失败留下损坏的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // GetSystemInfo 获取系统信息
 func GetSystemInfo(c *gin.Context) {
 	hostname, _ := os.Hostname()
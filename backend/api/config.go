@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"arsm/config"
 	"arsm/models"
@@ -52,6 +53,10 @@ var officialScenarios = []models.Scenario{
 	{ID: "{FA2AB0181129CB16}Missions/Scenario05_Hill.conf", Name: "Operation Omega 05: Cliffhanger", Map: "Kolguyev", Mode: "Campaign"},
 }
 
+// configMu 串行化对 config.json 和预设文件的读-改-写（保存配置、启用模组、应用模组包、写入名单等）。
+// 同时需要模组库时先持有 configMu 再持有 libraryMu
+var configMu sync.Mutex
+
 func getConfigPath() string {
 	cfg := config.Get()
 	return filepath.Join(cfg.ServerPath, "config.json")
@@ -187,11 +192,20 @@ func SaveConfig(c *gin.Context) {
 		return
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	configMu.Lock()
+	err = writeFileAtomic(configPath, data, 0644)
+	configMu.Unlock()
+	if err != nil {
 		fail(c, "保存配置失败")
 		return
 	}
 
+	// 名单管理的管理员以名单为准
+	if _, err := applyRoster(); err != nil {
+		fail(c, "配置已保存，但写入受信任玩家名单失败: "+err.Error())
+		return
+	}
+
 	success(c, nil)
 }
 
@@ -254,11 +268,20 @@ func SavePreset(c *gin.Context) {
 	presetPath := filepath.Join(presetsDir, req.Name+".json")
 	data, _ := json.MarshalIndent(req.Config, "", "    ")
 
-	if err := os.WriteFile(presetPath, data, 0644); err != nil {
+	configMu.Lock()
+	err := writeFileAtomic(presetPath, data, 0644)
+	configMu.Unlock()
+	if err != nil {
 		fail(c, "保存预设失败")
 		return
 	}
 
+	// 使用共享管理员列表的预设以名单为准
+	if _, err := applyRoster(); err != nil {
+		fail(c, "预设已保存，但写入共享管理员失败: "+err.Error())
+		return
+	}
+
 	success(c, nil)
 }
 
@@ -268,7 +291,11 @@ func DeletePreset(c *gin.Context) {
 	presetsDir := getPresetsDir()
 	presetPath := filepath.Join(presetsDir, name+".json")
 
-	if err := os.Remove(presetPath); err != nil {
+	// 与名单改写预设串行，避免删除后又被写回
+	configMu.Lock()
+	err := os.Remove(presetPath)
+	configMu.Unlock()
+	if err != nil {
 		fail(c, "删除预设失败")
 		return
 	}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arsm/config"
+)
+
+// testDir 测试使用的临时目录，HOME、数据目录和服务端目录都指向这里，避免读写真实配置
+var testDir = setupTestConfig()
+
+func setupTestConfig() string {
+	dir, err := os.MkdirTemp("", "arsm-api-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", dir)
+	os.Setenv("ARSM_DATA_DIR", filepath.Join(dir, "data"))
+	cfg := *config.Load()
+	cfg.ServerPath = filepath.Join(dir, "server")
+	cfg.SteamCMDPath = filepath.Join(dir, "steamcmd")
+	if err := config.Update(&cfg); err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// withConfig 修改配置副本并替换，测试结束后恢复（后台 goroutine 会并发读取配置）
+func withConfig(t *testing.T, modify func(cfg *config.AppConfig)) {
+	t.Helper()
+	old := config.Get()
+	cfg := *old
+	modify(&cfg)
+	if err := config.Update(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Update(old) })
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	os.RemoveAll(testDir)
+	os.Exit(code)
+}
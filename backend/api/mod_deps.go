@@ -0,0 +1,235 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// dependencyCycleError 依赖关系中存在循环
+type dependencyCycleError struct {
+	Path []string
+}
+
+func (e *dependencyCycleError) Error() string {
+	return "检测到循环依赖: " + strings.Join(e.Path, " -> ")
+}
+
+// modResolver 基于模组库和 addon 元数据解析模组依赖
+type modResolver struct {
+	lib  map[string]*models.Mod
+	deps map[string][]string // 直接依赖缓存
+}
+
+func newModResolver(libMods []models.Mod) *modResolver {
+	r := &modResolver{
+		lib:  make(map[string]*models.Mod, len(libMods)),
+		deps: make(map[string][]string),
+	}
+	for i := range libMods {
+		r.lib[libMods[i].ID] = &libMods[i]
+	}
+	return r
+}
+
+// depsOf 获取模组的直接依赖：模组库中手动录入的依赖与 addon 元数据中声明的依赖的并集
+func (r *modResolver) depsOf(id string) []string {
+	if deps, ok := r.deps[id]; ok {
+		return deps
+	}
+
+	var deps []string
+	seen := make(map[string]bool)
+	add := func(dep string) {
+		dep = strings.TrimSpace(dep)
+		if dep != "" && dep != id && !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	if mod, ok := r.lib[id]; ok {
+		for _, dep := range mod.Dependencies {
+			add(dep)
+		}
+	}
+	if dir := findAddonDir(id); dir != "" {
+		if meta, err := readAddonMeta(dir); err == nil {
+			for _, dep := range meta.dependencies() {
+				add(dep.ID)
+			}
+		}
+	}
+
+	r.deps[id] = deps
+	return deps
+}
+
+// closure 计算启用 id 所需的全部模组，依赖排在被依赖者之前，最后一个为 id 本身
+func (r *modResolver) closure(id string) ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var order []string
+	var stack []string
+
+	var visit func(string) error
+	visit = func(cur string) error {
+		switch state[cur] {
+		case done:
+			return nil
+		case visiting:
+			// 截取环上的路径用于提示
+			start := 0
+			for i, s := range stack {
+				if s == cur {
+					start = i
+					break
+				}
+			}
+			path := append(append([]string{}, stack[start:]...), cur)
+			return &dependencyCycleError{Path: path}
+		}
+
+		state[cur] = visiting
+		stack = append(stack, cur)
+		for _, dep := range r.depsOf(cur) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[cur] = done
+		order = append(order, cur)
+		return nil
+	}
+
+	if err := visit(id); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// dependsOn 判断 id 是否（直接或间接）依赖 target
+func (r *modResolver) dependsOn(id, target string) bool {
+	visited := make(map[string]bool)
+	var walk func(string) bool
+	walk = func(cur string) bool {
+		if visited[cur] {
+			return false
+		}
+		visited[cur] = true
+		for _, dep := range r.depsOf(cur) {
+			if dep == target || walk(dep) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(id)
+}
+
+// dependents 返回已启用模组中依赖 target 的模组
+func (r *modResolver) dependents(target string, enabled []models.ModConfig) []string {
+	var result []string
+	for _, m := range enabled {
+		if m.ModID != target && r.dependsOn(m.ModID, target) {
+			result = append(result, m.ModID)
+		}
+	}
+	return result
+}
+
+// modLabel 用于提示信息的模组名称
+func (r *modResolver) modLabel(id string) string {
+	if mod, ok := r.lib[id]; ok && mod.Name != "" {
+		return fmt.Sprintf("%s (%s)", mod.Name, id)
+	}
+	return id
+}
+
+// GetModDependencies 获取模组的依赖信息
+func GetModDependencies(c *gin.Context) {
+	id := c.Param("id")
+	libMods, _ := loadLibraryMods()
+	enabledMods, _ := loadEnabledMods()
+
+	resolver := newModResolver(libMods)
+	result := gin.H{
+		"direct":     resolver.depsOf(id),
+		"dependents": resolver.dependents(id, enabledMods),
+	}
+
+	closure, err := resolver.closure(id)
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		var missing []string
+		for _, dep := range closure {
+			if _, ok := resolver.lib[dep]; !ok {
+				missing = append(missing, dep)
+			}
+		}
+		result["closure"] = closure
+		result["missing"] = missing
+	}
+
+	success(c, result)
+}
+
+// SetModDependencies 手动设置模组依赖
+func SetModDependencies(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Dependencies []string `json:"dependencies"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的依赖数据")
+		return
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	libMods, _ := loadLibraryMods()
+	var target *models.Mod
+	for i := range libMods {
+		if libMods[i].ID == id {
+			target = &libMods[i]
+			break
+		}
+	}
+	if target == nil {
+		fail(c, "模组不存在")
+		return
+	}
+
+	var deps []string
+	seen := make(map[string]bool)
+	for _, dep := range req.Dependencies {
+		dep = strings.TrimSpace(dep)
+		if dep == id {
+			fail(c, "模组不能依赖自身")
+			return
+		}
+		if dep != "" && !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	target.Dependencies = deps
+
+	// 保存前检查是否引入循环依赖
+	if _, err := newModResolver(libMods).closure(id); err != nil {
+		fail(c, err.Error())
+		return
+	}
+
+	if err := saveLibraryMods(libMods); err != nil {
+		fail(c, "保存失败")
+		return
+	}
+	success(c, nil)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Workshop 模组 ID 为 16 位十六进制
+var (
+	modIDPattern      = regexp.MustCompile(`^[0-9A-Fa-f]{16}$`)
+	modIDInText       = regexp.MustCompile(`\b[0-9A-Fa-f]{16}\b`)
+	modVersionPattern = regexp.MustCompile(`^v?\d+(\.\d+)+$`)
+)
+
+// isValidModID 检查模组 ID 格式
+func isValidModID(id string) bool {
+	return modIDPattern.MatchString(id)
+}
+
+// ImportResultItem 导入结果中的单个模组
+type ImportResultItem struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Added   []ImportResultItem `json:"added"`
+	Present []ImportResultItem `json:"present"`
+	Invalid []ImportResultItem `json:"invalid"`
+}
+
+// parseModsJSON 解析 JSON 内容：服务端 config.json、预设文件或 game.mods 数组
+func parseModsJSON(content string) ([]models.ModConfig, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "[") {
+		var mods []models.ModConfig
+		if err := json.Unmarshal([]byte(content), &mods); err != nil {
+			return nil, errors.New("无法解析 game.mods 数组")
+		}
+		return mods, nil
+	}
+
+	var doc struct {
+		models.ServerConfig
+		// 预设导出格式 {"name": "...", "config": {...}}
+		Config *models.ServerConfig `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, errors.New("无法解析 JSON 配置")
+	}
+	if doc.Config != nil {
+		return doc.Config.Game.Mods, nil
+	}
+	return doc.Game.Mods, nil
+}
+
+// parseModsText 解析自由文本：每行一个模组 "ID[, 名称[, 版本]]"，或一行内用逗号分隔多个 ID
+// 不符合格式的行原样放入 invalid
+func parseModsText(content string) ([]models.ModConfig, []ImportResultItem) {
+	var mods []models.ModConfig
+	var invalid []ImportResultItem
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ';' || r == '|' || r == '\t'
+		})
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		// 一行内全部为 ID
+		allIDs := true
+		for _, f := range fields {
+			if f != "" && !isValidModID(f) {
+				allIDs = false
+				break
+			}
+		}
+		if allIDs {
+			for _, f := range fields {
+				if f != "" {
+					mods = append(mods, models.ModConfig{ModID: f})
+				}
+			}
+			continue
+		}
+
+		// "ID 名称 版本" 或 "ID, 名称, 版本"，ID 也可以是 Workshop 链接
+		if len(fields) == 1 {
+			fields = strings.SplitN(fields[0], " ", 2)
+		}
+		id := modIDInText.FindString(fields[0])
+		if id == "" {
+			invalid = append(invalid, ImportResultItem{ID: line, Reason: "未找到有效的模组 ID"})
+			continue
+		}
+		mod := models.ModConfig{ModID: id}
+		rest := fields[1:]
+		if n := len(rest); n > 0 && modVersionPattern.MatchString(rest[n-1]) {
+			mod.Version = strings.TrimPrefix(rest[n-1], "v")
+			rest = rest[:n-1]
+		} else if n == 1 {
+			// 名称后可能以空格跟版本号
+			if i := strings.LastIndex(rest[0], " "); i > 0 && modVersionPattern.MatchString(rest[0][i+1:]) {
+				mod.Version = strings.TrimPrefix(rest[0][i+1:], "v")
+				rest = []string{rest[0][:i]}
+			}
+		}
+		mod.Name = strings.TrimSpace(strings.Join(rest, " "))
+		mods = append(mods, mod)
+	}
+	return mods, invalid
+}
+
+// ImportMods 批量导入模组到 Library
+func ImportMods(c *gin.Context) {
+	var req struct {
+		Format  string `json:"format"` // auto / config / mods / preset / text
+		Content string `json:"content"`
+		Preset  string `json:"preset"` // 导入本地预设中的模组
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的导入数据")
+		return
+	}
+
+	var mods []models.ModConfig
+	var invalid []ImportResultItem
+	var err error
+
+	content := strings.TrimSpace(req.Content)
+	format := req.Format
+	if format == "" || format == "auto" {
+		switch {
+		case req.Preset != "":
+			format = "preset"
+		case strings.HasPrefix(content, "{"):
+			format = "config"
+		case strings.HasPrefix(content, "["):
+			format = "mods"
+		default:
+			format = "text"
+		}
+	}
+
+	switch format {
+	case "preset":
+		if req.Preset != "" {
+			data, readErr := os.ReadFile(filepath.Join(getPresetsDir(), filepath.Base(req.Preset)+".json"))
+			if readErr != nil {
+				fail(c, "预设不存在")
+				return
+			}
+			content = string(data)
+		}
+		mods, err = parseModsJSON(content)
+	case "config", "mods":
+		mods, err = parseModsJSON(content)
+	case "text":
+		mods, invalid = parseModsText(content)
+	default:
+		fail(c, "不支持的导入格式: "+format)
+		return
+	}
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	libMods, _ := loadLibraryMods()
+	existing := make(map[string]bool, len(libMods))
+	for _, m := range libMods {
+		existing[strings.ToUpper(m.ID)] = true
+	}
+
+	result := ImportResult{
+		Added:   []ImportResultItem{},
+		Present: []ImportResultItem{},
+		Invalid: []ImportResultItem{},
+	}
+	result.Invalid = append(result.Invalid, invalid...)
+
+	for _, m := range mods {
+		id := strings.ToUpper(strings.TrimSpace(m.ModID))
+		item := ImportResultItem{ID: id, Name: m.Name, Version: m.Version}
+		if !isValidModID(id) {
+			item.ID = m.ModID
+			item.Reason = "模组 ID 格式无效"
+			result.Invalid = append(result.Invalid, item)
+			continue
+		}
+		if existing[id] {
+			result.Present = append(result.Present, item)
+			continue
+		}
+		existing[id] = true
+		libMods = append(libMods, models.Mod{
+			ID:      id,
+			Name:    m.Name,
+			Version: m.Version,
+			AddedBy: currentUsername(c),
+			AddedAt: time.Now().Unix(),
+		})
+		result.Added = append(result.Added, item)
+	}
+
+	if len(result.Added) > 0 {
+		if err := saveLibraryMods(libMods); err != nil {
+			fail(c, "保存失败")
+			return
+		}
+	}
+	success(c, result)
+}
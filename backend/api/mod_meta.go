@@ -0,0 +1,204 @@
+package api
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"arsm/auth"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 每个模组保留的版本变更记录条数
+const maxModChangeLog = 50
+
+// normalizeTags 去除空白和重复的标签（不区分大小写）
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// hasTag 模组是否带有指定标签（不区分大小写）
+func hasTag(mod *models.Mod, tag string) bool {
+	for _, t := range mod.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDiskVersion 记录磁盘上看到的版本，版本变化时追加变更记录，返回是否有改动
+func recordDiskVersion(mod *models.Mod, version string, modTime int64) bool {
+	if version == "" || version == mod.DiskVersion {
+		return false
+	}
+	if mod.DiskVersion != "" {
+		mod.ChangeLog = append(mod.ChangeLog, models.ModChange{
+			Time:       time.Now().Unix(),
+			OldVersion: mod.DiskVersion,
+			NewVersion: version,
+		})
+		if len(mod.ChangeLog) > maxModChangeLog {
+			mod.ChangeLog = mod.ChangeLog[len(mod.ChangeLog)-maxModChangeLog:]
+		}
+	}
+	mod.DiskVersion = version
+	mod.DiskUpdatedAt = modTime
+	return true
+}
+
+// filterMods 按查询参数过滤模组列表：q（名称/ID）、tag（可多个或逗号分隔）、enabled、downloaded
+func filterMods(c *gin.Context, mods []models.ModView) []models.ModView {
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	var tags []string
+	for _, t := range c.QueryArray("tag") {
+		tags = append(tags, strings.Split(t, ",")...)
+	}
+	tags = normalizeTags(tags)
+	enabled := c.Query("enabled")
+	downloaded := c.Query("downloaded")
+
+	result := []models.ModView{}
+	for i := range mods {
+		mod := &mods[i]
+		if q != "" && !strings.Contains(strings.ToLower(mod.Name), q) && !strings.Contains(strings.ToLower(mod.ID), q) {
+			continue
+		}
+		if enabled != "" && (enabled == "true") != mod.Enabled {
+			continue
+		}
+		if downloaded != "" && (downloaded == "true") != mod.Downloaded {
+			continue
+		}
+		matched := true
+		for _, tag := range tags {
+			if !hasTag(&mod.Mod, tag) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, *mod)
+		}
+	}
+	return result
+}
+
+// UpdateMod 更新模组库中的模组信息（名称、版本、备注、标签等）
+func UpdateMod(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Name        *string   `json:"name"`
+		Version     *string   `json:"version"`
+		Notes       *string   `json:"notes"`
+		RequestedBy *string   `json:"requested_by"`
+		Tags        *[]string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的模组数据")
+		return
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	libMods, _ := loadLibraryMods()
+	var mod *models.Mod
+	for i := range libMods {
+		if libMods[i].ID == id {
+			mod = &libMods[i]
+			break
+		}
+	}
+	if mod == nil {
+		fail(c, "模组不存在")
+		return
+	}
+
+	if req.Name != nil {
+		mod.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Version != nil {
+		mod.Version = strings.TrimSpace(*req.Version)
+	}
+	if req.Notes != nil {
+		mod.Notes = *req.Notes
+	}
+	if req.RequestedBy != nil {
+		mod.RequestedBy = strings.TrimSpace(*req.RequestedBy)
+	}
+	if req.Tags != nil {
+		mod.Tags = normalizeTags(*req.Tags)
+	}
+
+	if err := saveLibraryMods(libMods); err != nil {
+		fail(c, "保存失败")
+		return
+	}
+	success(c, mod)
+}
+
+// GetModTags 获取模组库中使用的全部标签及数量
+func GetModTags(c *gin.Context) {
+	libMods, _ := loadLibraryMods()
+	counts := make(map[string]int)
+	names := make(map[string]string)
+	for _, mod := range libMods {
+		for _, tag := range mod.Tags {
+			key := strings.ToLower(tag)
+			if _, ok := names[key]; !ok {
+				names[key] = tag
+			}
+			counts[key]++
+		}
+	}
+
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Count int    `json:"count"`
+	}
+	result := make([]tagCount, 0, len(counts))
+	for key, n := range counts {
+		result = append(result, tagCount{Tag: names[key], Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	success(c, result)
+}
+
+// GetModChangeLog 获取模组在磁盘上的版本变更记录
+func GetModChangeLog(c *gin.Context) {
+	id := c.Param("id")
+	libMods, _ := loadLibraryMods()
+	for _, mod := range libMods {
+		if mod.ID == id {
+			changes := mod.ChangeLog
+			if changes == nil {
+				changes = []models.ModChange{}
+			}
+			success(c, gin.H{
+				"disk_version":    mod.DiskVersion,
+				"disk_updated_at": mod.DiskUpdatedAt,
+				"changes":         changes,
+			})
+			return
+		}
+	}
+	fail(c, "模组不存在")
+}
+
+// currentUsername 当前登录的 ARSM 用户名
+func currentUsername(c *gin.Context) string {
+	username, _, _ := auth.GetCurrentUser(c)
+	return username
+}
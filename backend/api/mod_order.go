@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 记住的加载顺序（包含已禁用的模组），用于重新启用时恢复原位置
+func getModOrderPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_mods_order.json")
+}
+
+func loadModOrder() []string {
+	data, err := os.ReadFile(getModOrderPath())
+	if err != nil {
+		return []string{}
+	}
+	var order []string
+	if err := json.Unmarshal(data, &order); err != nil {
+		return []string{}
+	}
+	return order
+}
+
+func saveModOrder(order []string) error {
+	data, err := json.MarshalIndent(order, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getModOrderPath(), data, 0644)
+}
+
+// mergeModOrder 以当前启用顺序为准，将之前记住但未启用的模组放回其前一个模组之后
+func mergeModOrder(remembered []string, enabled []models.ModConfig) []string {
+	result := make([]string, 0, len(remembered)+len(enabled))
+	inResult := make(map[string]bool)
+	for _, m := range enabled {
+		if !inResult[m.ModID] {
+			result = append(result, m.ModID)
+			inResult[m.ModID] = true
+		}
+	}
+
+	for i, id := range remembered {
+		if inResult[id] {
+			continue
+		}
+		// 找到记住顺序中最近的、已在结果中的前一个模组
+		pos := 0
+		for j := i - 1; j >= 0; j-- {
+			if !inResult[remembered[j]] {
+				continue
+			}
+			for k, r := range result {
+				if r == remembered[j] {
+					pos = k + 1
+					break
+				}
+			}
+			break
+		}
+		result = append(result, "")
+		copy(result[pos+1:], result[pos:])
+		result[pos] = id
+		inResult[id] = true
+	}
+	return result
+}
+
+// rememberModOrder 将启用顺序合并到记住的加载顺序中
+func rememberModOrder(enabled []models.ModConfig) error {
+	return saveModOrder(mergeModOrder(loadModOrder(), enabled))
+}
+
+// forgetModOrder 从记住的加载顺序中移除模组
+func forgetModOrder(id string) error {
+	order := loadModOrder()
+	result := order[:0]
+	for _, m := range order {
+		if m != id {
+			result = append(result, m)
+		}
+	}
+	return saveModOrder(result)
+}
+
+// insertEnabledMod 按记住的加载顺序将模组插入启用列表
+func insertEnabledMod(enabled []models.ModConfig, mod models.ModConfig, remembered []string) []models.ModConfig {
+	rank := make(map[string]int, len(remembered))
+	for i, id := range remembered {
+		rank[id] = i
+	}
+	target, ok := rank[mod.ModID]
+	if !ok {
+		return append(enabled, mod)
+	}
+
+	// 插入到记住顺序中排在它之前的最后一个已启用模组之后
+	pos := 0
+	for i, m := range enabled {
+		if r, ok := rank[m.ModID]; ok && r < target {
+			pos = i + 1
+		}
+	}
+	enabled = append(enabled, models.ModConfig{})
+	copy(enabled[pos+1:], enabled[pos:])
+	enabled[pos] = mod
+	return enabled
+}
+
+// ensureAfterDependencies 确保模组排在其依赖之后，必要时将其移到最后一个依赖之后
+func ensureAfterDependencies(enabled []models.ModConfig, id string, resolver *modResolver) []models.ModConfig {
+	index := func(modID string) int {
+		for i, m := range enabled {
+			if m.ModID == modID {
+				return i
+			}
+		}
+		return -1
+	}
+
+	cur := index(id)
+	if cur < 0 {
+		return enabled
+	}
+	last := -1
+	for _, dep := range resolver.depsOf(id) {
+		if i := index(dep); i > last {
+			last = i
+		}
+	}
+	if last < cur {
+		return enabled
+	}
+
+	mod := enabled[cur]
+	enabled = append(enabled[:cur], enabled[cur+1:]...)
+	// 移除后最后一个依赖的下标前移一位
+	pos := last
+	enabled = append(enabled, models.ModConfig{})
+	copy(enabled[pos+1:], enabled[pos:])
+	enabled[pos] = mod
+	return enabled
+}
+
+// topoSortMods 按依赖关系排序，依赖在前；尽量保持当前顺序，只把依赖提前到使用它的模组之前
+func topoSortMods(enabled []models.ModConfig, resolver *modResolver) ([]models.ModConfig, error) {
+	byID := make(map[string]models.ModConfig, len(enabled))
+	for _, m := range enabled {
+		byID[m.ModID] = m
+	}
+
+	placed := make(map[string]bool, len(enabled))
+	result := make([]models.ModConfig, 0, len(enabled))
+	for _, m := range enabled {
+		closure, err := resolver.closure(m.ModID)
+		if err != nil {
+			return nil, err
+		}
+		// closure 中依赖在前，只放入已启用且尚未放置的模组
+		for _, id := range closure {
+			if dep, ok := byID[id]; ok && !placed[id] {
+				placed[id] = true
+				result = append(result, dep)
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetModOrder 获取启用模组的加载顺序
+func GetModOrder(c *gin.Context) {
+	enabledMods, _ := loadEnabledMods()
+	order := make([]string, 0, len(enabledMods))
+	for _, m := range enabledMods {
+		order = append(order, m.ModID)
+	}
+	success(c, order)
+}
+
+// SetModOrder 设置全部启用模组的加载顺序
+func SetModOrder(c *gin.Context) {
+	var req struct {
+		Order []string `json:"order"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的排序数据")
+		return
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	enabledMods, _ := loadEnabledMods()
+	byID := make(map[string]models.ModConfig, len(enabledMods))
+	for _, m := range enabledMods {
+		byID[m.ModID] = m
+	}
+	if len(req.Order) != len(enabledMods) {
+		fail(c, "排序必须包含全部已启用的模组")
+		return
+	}
+
+	reordered := make([]models.ModConfig, 0, len(req.Order))
+	seen := make(map[string]bool)
+	for _, id := range req.Order {
+		m, ok := byID[id]
+		if !ok || seen[id] {
+			fail(c, "排序中包含未启用或重复的模组: "+id)
+			return
+		}
+		seen[id] = true
+		reordered = append(reordered, m)
+	}
+
+	if err := saveEnabledMods(reordered); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// MoveMod 将启用模组上移或下移一位
+func MoveMod(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Direction string `json:"direction"` // up / down
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Direction != "up" && req.Direction != "down") {
+		fail(c, "direction 必须为 up 或 down")
+		return
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	enabledMods, _ := loadEnabledMods()
+	cur := -1
+	for i, m := range enabledMods {
+		if m.ModID == id {
+			cur = i
+			break
+		}
+	}
+	if cur < 0 {
+		fail(c, "模组未启用")
+		return
+	}
+
+	next := cur - 1
+	if req.Direction == "down" {
+		next = cur + 1
+	}
+	if next < 0 || next >= len(enabledMods) {
+		success(c, nil)
+		return
+	}
+	enabledMods[cur], enabledMods[next] = enabledMods[next], enabledMods[cur]
+
+	if err := saveEnabledMods(enabledMods); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// AutoSortMods 按依赖关系自动排序启用模组
+func AutoSortMods(c *gin.Context) {
+	libMods, _ := loadLibraryMods()
+	configMu.Lock()
+	defer configMu.Unlock()
+	enabledMods, _ := loadEnabledMods()
+
+	sorted, err := topoSortMods(enabledMods, newModResolver(libMods))
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+	if err := saveEnabledMods(sorted); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+
+	order := make([]string, 0, len(sorted))
+	for _, m := range sorted {
+		order = append(order, m.ModID)
+	}
+	success(c, order)
+}
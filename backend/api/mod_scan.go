@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+)
+
+// 扫描同一时间只运行一次
+var scanMu sync.Mutex
+
+// AddonScanResult 扫描 addons 目录的结果
+type AddonScanResult struct {
+	Added    []models.AddonInfo `json:"added"`
+	Updated  []models.AddonInfo `json:"updated"`
+	Orphaned []string           `json:"orphaned"`
+	Skipped  []string           `json:"skipped"` // 无法识别模组 ID 的目录
+}
+
+// addonIDFromDir 从目录名推断模组 ID：<id> 或 <Name>_<id>
+func addonIDFromDir(name string) string {
+	if isValidModID(name) {
+		return strings.ToUpper(name)
+	}
+	if i := strings.LastIndex(name, "_"); i >= 0 && isValidModID(name[i+1:]) {
+		return strings.ToUpper(name[i+1:])
+	}
+	return ""
+}
+
+// scanAddons 扫描 addons 目录，新增模组库条目、记录磁盘版本，并标记文件已缺失的条目
+func scanAddons() (*AddonScanResult, error) {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	result := &AddonScanResult{
+		Added:    []models.AddonInfo{},
+		Updated:  []models.AddonInfo{},
+		Orphaned: []string{},
+		Skipped:  []string{},
+	}
+
+	addonsDir := getAddonsDir()
+	entries, err := os.ReadDir(addonsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// 磁盘上的 addon
+	found := make(map[string]models.AddonInfo)
+	var foundOrder []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(addonsDir, entry.Name())
+		info := models.AddonInfo{Path: dir}
+		if meta, err := readAddonMeta(dir); err == nil {
+			info.HasMetadata = true
+			info.ID = strings.ToUpper(meta.ID)
+			info.Name = meta.Name
+			info.Version = meta.version()
+		}
+		if !isValidModID(info.ID) {
+			info.ID = addonIDFromDir(entry.Name())
+		}
+		if info.ID == "" {
+			result.Skipped = append(result.Skipped, entry.Name())
+			continue
+		}
+		if info.Name == "" {
+			info.Name = strings.TrimSuffix(entry.Name(), "_"+info.ID)
+		}
+		if _, dup := found[info.ID]; dup {
+			continue
+		}
+		info.Size, info.LastModified = refreshAddonDiskUsage(dir)
+		found[info.ID] = info
+		foundOrder = append(foundOrder, info.ID)
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	libMods, _ := loadLibraryMods()
+	changed := false
+	inLibrary := make(map[string]bool, len(libMods))
+	for i := range libMods {
+		mod := &libMods[i]
+		id := strings.ToUpper(mod.ID)
+		inLibrary[id] = true
+
+		addon, ok := found[id]
+		if !ok {
+			if !mod.Orphaned {
+				mod.Orphaned = true
+				changed = true
+			}
+			result.Orphaned = append(result.Orphaned, mod.ID)
+			continue
+		}
+
+		updated := false
+		if mod.Orphaned {
+			mod.Orphaned = false
+			updated = true
+		}
+		if mod.Name == "" && addon.Name != "" {
+			mod.Name = addon.Name
+			updated = true
+		}
+		// Version 是启用时写入 config.json 的锁定版本，不随磁盘变化，只记录磁盘版本
+		if recordDiskVersion(mod, addon.Version, addon.LastModified) {
+			updated = true
+		}
+		if updated {
+			changed = true
+			result.Updated = append(result.Updated, addon)
+		}
+	}
+
+	for _, id := range foundOrder {
+		if inLibrary[id] {
+			continue
+		}
+		addon := found[id]
+		mod := models.Mod{ID: addon.ID, Name: addon.Name, Version: addon.Version, AddedAt: time.Now().Unix()}
+		recordDiskVersion(&mod, addon.Version, addon.LastModified)
+		libMods = append(libMods, mod)
+		result.Added = append(result.Added, addon)
+		changed = true
+	}
+
+	if changed {
+		if err := saveLibraryMods(libMods); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ScanAddons 扫描 addons 目录并同步模组库
+func ScanAddons(c *gin.Context) {
+	result, err := scanAddons()
+	if err != nil {
+		fail(c, "扫描失败: "+err.Error())
+		return
+	}
+	success(c, result)
+}
+
+// StartAddonScanner 按设置中的间隔定期扫描 addons 目录
+func StartAddonScanner() {
+	go func() {
+		var lastScan time.Time
+		for {
+			time.Sleep(time.Minute)
+			interval := config.Get().AddonScanInterval
+			if interval <= 0 || time.Since(lastScan) < time.Duration(interval)*time.Minute {
+				continue
+			}
+			lastScan = time.Now()
+			result, err := scanAddons()
+			if err != nil {
+				ws.Broadcast("[模组扫描] 失败: " + err.Error())
+				continue
+			}
+			if len(result.Added) > 0 || len(result.Updated) > 0 {
+				ws.Broadcast(fmt.Sprintf("[模组扫描] 新增 %d 个，更新 %d 个模组", len(result.Added), len(result.Updated)))
+			}
+		}
+	}()
+}
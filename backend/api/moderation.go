@@ -0,0 +1,397 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"arsm/store"
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+const bucketModerationLog = "moderation_log" // 序号 -> ModerationAction
+
+// 自动管理记录的保留上限：执行失败时每轮都会记录，超过条数或时间的最早记录在写入新记录时删除
+const (
+	maxModerationLogEntries = 20000
+	moderationLogRetention  = 90 * 24 * time.Hour
+)
+
+// 规则类型
+const (
+	moderationPing = "ping"
+	moderationName = "name"
+	moderationIdle = "idle"
+)
+
+// 各类规则的默认值
+const (
+	defaultPingSamples     = 3
+	defaultPingKickReason  = "High ping"
+	defaultNameKickReason  = "Inappropriate name"
+	defaultIdleKickReason  = "Idle in lobby"
+	defaultIdleWarnMessage = "You will be kicked for idling in the lobby"
+	moderationActionWarn   = "warn"
+	moderationActionKick   = "kick"
+)
+
+// moderationPlayer 某个在线玩家的规则状态，玩家离线后清除
+type moderationPlayer struct {
+	strikes    map[string]int  // ping 规则名 -> 连续超过阈值的次数
+	lobbySince time.Time       // 开始停留在大厅的时间
+	done       map[string]bool // 规则名/动作 -> 本次在线期间已处理（试运行也只记录一次）
+}
+
+// moderationTask 一次待执行的操作
+type moderationTask struct {
+	key    string // moderationPlayers 中的玩家键
+	rule   models.ModerationRule
+	action string
+	player models.Player
+	detail string
+}
+
+var (
+	moderationMu      sync.Mutex
+	moderationPlayers = make(map[string]*moderationPlayer)
+)
+
+// 自动管理规则文件
+func getModerationRulesPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_moderation.json")
+}
+
+func loadModerationRules() []models.ModerationRule {
+	data, err := os.ReadFile(getModerationRulesPath())
+	if err != nil {
+		return []models.ModerationRule{}
+	}
+	var rules []models.ModerationRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return []models.ModerationRule{}
+	}
+	return rules
+}
+
+func saveModerationRules(rules []models.ModerationRule) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getModerationRulesPath(), data, 0644)
+}
+
+// validateModerationRule 检查规则并补充默认值
+func validateModerationRule(r *models.ModerationRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("规则名称不能为空")
+	}
+	r.Reason = strings.Join(strings.Fields(r.Reason), " ")
+	switch r.Type {
+	case moderationPing:
+		if r.MaxPing <= 0 {
+			return fmt.Errorf("规则 %s: 延迟阈值必须大于 0", r.Name)
+		}
+		if r.Samples <= 0 {
+			r.Samples = defaultPingSamples
+		}
+		if r.Reason == "" {
+			r.Reason = defaultPingKickReason
+		}
+	case moderationName:
+		words := make([]string, 0, len(r.Words))
+		for _, w := range r.Words {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, w)
+			}
+		}
+		r.Words = words
+		if len(words) == 0 && r.Pattern == "" {
+			return fmt.Errorf("规则 %s: 屏蔽词和正则至少设置一项", r.Name)
+		}
+		if r.Pattern != "" {
+			if _, err := regexp.Compile("(?i)" + r.Pattern); err != nil {
+				return fmt.Errorf("规则 %s: 无效的正则: %v", r.Name, err)
+			}
+		}
+		if r.Reason == "" {
+			r.Reason = defaultNameKickReason
+		}
+	case moderationIdle:
+		if r.WarnAfter < 0 || r.KickAfter < 0 || r.WarnAfter == 0 && r.KickAfter == 0 {
+			return fmt.Errorf("规则 %s: 警告时间和踢出时间至少设置一项", r.Name)
+		}
+		if r.WarnAfter > 0 && r.KickAfter > 0 && r.WarnAfter >= r.KickAfter {
+			return fmt.Errorf("规则 %s: 警告时间必须早于踢出时间", r.Name)
+		}
+		r.WarnMessage = strings.Join(strings.Fields(r.WarnMessage), " ")
+		if r.WarnMessage == "" {
+			r.WarnMessage = defaultIdleWarnMessage
+		}
+		if r.Reason == "" {
+			r.Reason = defaultIdleKickReason
+		}
+	default:
+		return fmt.Errorf("规则 %s: 类型只能是 ping、name 或 idle", r.Name)
+	}
+	return nil
+}
+
+// matchBlockedName 名称命中的屏蔽词或正则，未命中返回空
+func matchBlockedName(r models.ModerationRule, name string) string {
+	lower := strings.ToLower(name)
+	for _, w := range r.Words {
+		if strings.Contains(lower, strings.ToLower(w)) {
+			return "屏蔽词: " + w
+		}
+	}
+	if r.Pattern != "" {
+		if re, err := regexp.Compile("(?i)" + r.Pattern); err == nil && re.MatchString(name) {
+			return "匹配正则: " + r.Pattern
+		}
+	}
+	return ""
+}
+
+// moderationKey 玩家状态的键，没有持久标识时使用会话编号和名称
+func moderationKey(p models.Player) string {
+	if id := playerIdentity(p); id != "" {
+		return id
+	}
+	return "#" + p.ID + " " + p.Name
+}
+
+// evaluateModeration 更新玩家状态并返回需要执行的操作
+func evaluateModeration(rules []models.ModerationRule, players []models.Player, now time.Time) []moderationTask {
+	roster := loadRoster()
+
+	moderationMu.Lock()
+	defer moderationMu.Unlock()
+
+	var tasks []moderationTask
+	seen := make(map[string]bool, len(players))
+	for _, p := range players {
+		key := moderationKey(p)
+		seen[key] = true
+		st := moderationPlayers[key]
+		if st == nil {
+			st = &moderationPlayer{strikes: make(map[string]int), done: make(map[string]bool)}
+			moderationPlayers[key] = st
+		}
+		// 名单中的受信任玩家不受规则限制
+		if rosterTrusted(roster, p, now.Unix()) {
+			continue
+		}
+		if !p.Lobby {
+			st.lobbySince = time.Time{}
+		} else if st.lobbySince.IsZero() {
+			st.lobbySince = now
+		}
+
+		// once 每个玩家每条规则的每种操作只处理一次，执行成功后由 markModerationDone 标记，失败时下一轮重试
+		once := func(r models.ModerationRule, action, detail string) {
+			if !st.done[r.Name+"/"+action] {
+				tasks = append(tasks, moderationTask{key: key, rule: r, action: action, player: p, detail: detail})
+			}
+		}
+		for _, r := range rules {
+			if !r.Enabled {
+				continue
+			}
+			switch r.Type {
+			case moderationPing:
+				// 延迟未知时不计入采样
+				if p.Ping <= 0 {
+					continue
+				}
+				if p.Ping <= r.MaxPing {
+					st.strikes[r.Name] = 0
+					continue
+				}
+				st.strikes[r.Name]++
+				if st.strikes[r.Name] >= r.Samples {
+					once(r, moderationActionKick, fmt.Sprintf("延迟 %dms，连续 %d 次超过 %dms", p.Ping, st.strikes[r.Name], r.MaxPing))
+				}
+			case moderationName:
+				if reason := matchBlockedName(r, p.Name); reason != "" {
+					once(r, moderationActionKick, reason)
+				}
+			case moderationIdle:
+				if st.lobbySince.IsZero() {
+					continue
+				}
+				idle := now.Sub(st.lobbySince)
+				detail := fmt.Sprintf("已在大厅停留 %d 分钟", int(idle.Minutes()))
+				if r.KickAfter > 0 && idle >= time.Duration(r.KickAfter)*time.Minute {
+					once(r, moderationActionKick, detail)
+				} else if r.WarnAfter > 0 && idle >= time.Duration(r.WarnAfter)*time.Minute {
+					once(r, moderationActionWarn, detail)
+				}
+			}
+		}
+	}
+	for key := range moderationPlayers {
+		if !seen[key] {
+			delete(moderationPlayers, key)
+		}
+	}
+	return tasks
+}
+
+// markModerationDone 标记玩家本次在线期间已处理该操作；玩家已离线时忽略
+func markModerationDone(t moderationTask) {
+	moderationMu.Lock()
+	defer moderationMu.Unlock()
+	if st := moderationPlayers[t.key]; st != nil {
+		st.done[t.rule.Name+"/"+t.action] = true
+	}
+}
+
+// recordModerationAction 追加一条自动管理记录，写入失败只输出日志
+func recordModerationAction(action models.ModerationAction) {
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketModerationLog))
+		if err != nil {
+			return err
+		}
+		if action.ID, err = b.NextSequence(); err != nil {
+			return err
+		}
+		if err := store.PutJSON(b, store.Itob(action.ID), action); err != nil {
+			return err
+		}
+		return pruneLogBucket(b, action.ID, action.Time, maxModerationLogEntries, moderationLogRetention)
+	})
+	if err != nil {
+		fmt.Printf("[ARSM] 写入自动管理记录失败: %v\n", err)
+	}
+}
+
+// runModeration 按规则处理一次玩家列表，由定时获取玩家列表时调用
+func runModeration(players []models.Player, now time.Time) {
+	rules := loadModerationRules()
+	if len(rules) == 0 {
+		return
+	}
+	kicked := make(map[string]bool)
+	for _, t := range evaluateModeration(rules, players, now) {
+		// 同一轮中玩家已被踢出时不再处理其他规则
+		if kicked[t.player.ID] {
+			continue
+		}
+		action := models.ModerationAction{
+			Time:       now.Unix(),
+			Rule:       t.rule.Name,
+			Action:     t.action,
+			PlayerID:   t.player.ID,
+			PlayerName: t.player.Name,
+			Identity:   playerIdentity(t.player),
+			Detail:     t.detail,
+			DryRun:     t.rule.DryRun,
+		}
+		if !t.rule.DryRun {
+			var err error
+			if t.action == moderationActionWarn {
+				err = messagePlayer(rconSystemUser, t.player.ID, t.rule.WarnMessage)
+			} else {
+				err = kickPlayer(rconSystemUser, t.player.ID, t.rule.Reason)
+			}
+			if err != nil {
+				action.Error = err.Error()
+			} else if t.action == moderationActionKick {
+				kicked[t.player.ID] = true
+			}
+		}
+		if action.Error == "" {
+			markModerationDone(t)
+		}
+		recordModerationAction(action)
+	}
+}
+
+// GetModerationRules 获取自动管理规则
+func GetModerationRules(c *gin.Context) {
+	success(c, loadModerationRules())
+}
+
+// SaveModerationRules 保存自动管理规则（仅管理员，整体替换）
+func SaveModerationRules(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var rules []models.ModerationRule
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		fail(c, "无效的规则数据")
+		return
+	}
+	seen := make(map[string]bool)
+	for i := range rules {
+		if err := validateModerationRule(&rules[i]); err != nil {
+			fail(c, err.Error())
+			return
+		}
+		if seen[rules[i].Name] {
+			fail(c, "规则名称重复: "+rules[i].Name)
+			return
+		}
+		seen[rules[i].Name] = true
+	}
+	if err := saveModerationRules(rules); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, rules)
+}
+
+// GetModerationLog 获取自动管理记录，从新到旧；可按 rule、player（名称或标识）过滤
+func GetModerationLog(c *gin.Context) {
+	rule := c.Query("rule")
+	player := strings.ToLower(c.Query("player"))
+	before, _ := strconv.ParseUint(c.Query("before"), 10, 64)
+	limit := queryInt(c, "limit", 50, 500)
+
+	actions := []models.ModerationAction{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketModerationLog))
+		if b == nil {
+			return nil
+		}
+		cur := b.Cursor()
+		k, v := cur.Last()
+		if before > 0 {
+			if sk, _ := cur.Seek(store.Itob(before)); sk != nil {
+				k, v = cur.Prev()
+			}
+		}
+		for ; k != nil && len(actions) < limit; k, v = cur.Prev() {
+			var a models.ModerationAction
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			if rule != "" && a.Rule != rule {
+				continue
+			}
+			if player != "" && !strings.Contains(strings.ToLower(a.PlayerName), player) && a.Identity != player {
+				continue
+			}
+			actions = append(actions, a)
+		}
+		return nil
+	})
+	if err != nil {
+		fail(c, "读取自动管理记录失败: "+err.Error())
+		return
+	}
+	success(c, actions)
+}
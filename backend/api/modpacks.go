@@ -0,0 +1,308 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 模组包文件的读-改-写需持有该锁，避免并发修改时丢失改动
+var modpackMu sync.Mutex
+
+// 模组包文件，与模组库放在一起
+func getModpacksPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_modpacks.json")
+}
+
+func loadModpacks() ([]models.Modpack, error) {
+	data, err := os.ReadFile(getModpacksPath())
+	if err != nil {
+		return []models.Modpack{}, nil
+	}
+	var packs []models.Modpack
+	if err := json.Unmarshal(data, &packs); err != nil {
+		return []models.Modpack{}, nil
+	}
+	return packs, nil
+}
+
+func saveModpacks(packs []models.Modpack) error {
+	data, err := json.MarshalIndent(packs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getModpacksPath(), data, 0644)
+}
+
+func findModpack(packs []models.Modpack, name string) int {
+	for i := range packs {
+		if packs[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeModpackMods 去除空 ID 和重复项，并从模组库补全名称
+func normalizeModpackMods(mods []models.ModConfig) ([]models.ModConfig, error) {
+	libMods, _ := loadLibraryMods()
+	names := make(map[string]string, len(libMods))
+	for _, m := range libMods {
+		names[m.ID] = m.Name
+	}
+
+	result := make([]models.ModConfig, 0, len(mods))
+	seen := make(map[string]bool)
+	for _, m := range mods {
+		m.ModID = strings.TrimSpace(m.ModID)
+		if m.ModID == "" {
+			continue
+		}
+		if seen[m.ModID] {
+			return nil, fmt.Errorf("模组重复: %s", m.ModID)
+		}
+		seen[m.ModID] = true
+		if m.Name == "" {
+			m.Name = names[m.ModID]
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// diffModLists 计算从 current 切换到 target 时的差异
+func diffModLists(current, target []models.ModConfig) models.ModpackDiff {
+	diff := models.ModpackDiff{
+		Added:          []models.ModConfig{},
+		Removed:        []models.ModConfig{},
+		VersionChanged: []models.ModConfig{},
+		Unchanged:      []models.ModConfig{},
+	}
+
+	currentByID := make(map[string]models.ModConfig, len(current))
+	for _, m := range current {
+		currentByID[m.ModID] = m
+	}
+	targetSet := make(map[string]bool, len(target))
+	var keptTarget []string
+	for _, m := range target {
+		targetSet[m.ModID] = true
+		old, ok := currentByID[m.ModID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, m)
+		case old.Version != m.Version:
+			diff.VersionChanged = append(diff.VersionChanged, m)
+			keptTarget = append(keptTarget, m.ModID)
+		default:
+			diff.Unchanged = append(diff.Unchanged, m)
+			keptTarget = append(keptTarget, m.ModID)
+		}
+	}
+
+	var keptCurrent []string
+	for _, m := range current {
+		if !targetSet[m.ModID] {
+			diff.Removed = append(diff.Removed, m)
+		} else {
+			keptCurrent = append(keptCurrent, m.ModID)
+		}
+	}
+
+	// 两边都保留的模组相对顺序是否发生变化；config.json 中有重复的模组时数量不一致，同样视为需要重新排列
+	if len(keptCurrent) != len(keptTarget) {
+		diff.OrderChanged = true
+		return diff
+	}
+	for i := range keptCurrent {
+		if keptCurrent[i] != keptTarget[i] {
+			diff.OrderChanged = true
+			break
+		}
+	}
+	return diff
+}
+
+// GetModpacks 获取模组包列表
+func GetModpacks(c *gin.Context) {
+	packs, _ := loadModpacks()
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	success(c, packs)
+}
+
+// GetModpack 获取指定模组包
+func GetModpack(c *gin.Context) {
+	packs, _ := loadModpacks()
+	i := findModpack(packs, c.Param("name"))
+	if i < 0 {
+		fail(c, "模组包不存在")
+		return
+	}
+	success(c, packs[i])
+}
+
+// CreateModpack 创建模组包；from_current 为 true 时使用当前启用的模组
+func CreateModpack(c *gin.Context) {
+	var req struct {
+		Name        string             `json:"name"`
+		Description string             `json:"description"`
+		Mods        []models.ModConfig `json:"mods"`
+		FromCurrent bool               `json:"from_current"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的模组包数据")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		fail(c, "模组包名称不能为空")
+		return
+	}
+
+	modpackMu.Lock()
+	defer modpackMu.Unlock()
+	packs, _ := loadModpacks()
+	if findModpack(packs, req.Name) >= 0 {
+		fail(c, "模组包已存在")
+		return
+	}
+
+	mods := req.Mods
+	if req.FromCurrent {
+		mods, _ = loadEnabledMods()
+	}
+	mods, err := normalizeModpackMods(mods)
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+
+	now := time.Now().Unix()
+	packs = append(packs, models.Modpack{
+		Name:        req.Name,
+		Description: req.Description,
+		Mods:        mods,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err := saveModpacks(packs); err != nil {
+		fail(c, "保存失败")
+		return
+	}
+	success(c, nil)
+}
+
+// UpdateModpack 更新模组包
+func UpdateModpack(c *gin.Context) {
+	name := c.Param("name")
+	var req struct {
+		Name        string             `json:"name"`
+		Description string             `json:"description"`
+		Mods        []models.ModConfig `json:"mods"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的模组包数据")
+		return
+	}
+
+	modpackMu.Lock()
+	defer modpackMu.Unlock()
+	packs, _ := loadModpacks()
+	i := findModpack(packs, name)
+	if i < 0 {
+		fail(c, "模组包不存在")
+		return
+	}
+
+	// 支持重命名
+	newName := strings.TrimSpace(req.Name)
+	if newName == "" {
+		newName = name
+	}
+	if newName != name && findModpack(packs, newName) >= 0 {
+		fail(c, "模组包已存在")
+		return
+	}
+
+	mods, err := normalizeModpackMods(req.Mods)
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+
+	packs[i].Name = newName
+	packs[i].Description = req.Description
+	packs[i].Mods = mods
+	packs[i].UpdatedAt = time.Now().Unix()
+	if err := saveModpacks(packs); err != nil {
+		fail(c, "保存失败")
+		return
+	}
+	success(c, nil)
+}
+
+// DeleteModpack 删除模组包
+func DeleteModpack(c *gin.Context) {
+	modpackMu.Lock()
+	defer modpackMu.Unlock()
+	packs, _ := loadModpacks()
+	i := findModpack(packs, c.Param("name"))
+	if i < 0 {
+		fail(c, "模组包不存在")
+		return
+	}
+	packs = append(packs[:i], packs[i+1:]...)
+	if err := saveModpacks(packs); err != nil {
+		fail(c, "删除失败")
+		return
+	}
+	success(c, nil)
+}
+
+// PreviewModpack 预览应用模组包后 game.mods 的变化
+func PreviewModpack(c *gin.Context) {
+	packs, _ := loadModpacks()
+	i := findModpack(packs, c.Param("name"))
+	if i < 0 {
+		fail(c, "模组包不存在")
+		return
+	}
+	enabledMods, _ := loadEnabledMods()
+	success(c, diffModLists(enabledMods, packs[i].Mods))
+}
+
+// ApplyModpack 用模组包一次性替换 game.mods
+func ApplyModpack(c *gin.Context) {
+	packs, _ := loadModpacks()
+	i := findModpack(packs, c.Param("name"))
+	if i < 0 {
+		fail(c, "模组包不存在")
+		return
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	if _, err := os.Stat(getConfigPath()); err != nil {
+		fail(c, "config.json 不存在，请先保存服务端配置")
+		return
+	}
+
+	enabledMods, _ := loadEnabledMods()
+	diff := diffModLists(enabledMods, packs[i].Mods)
+
+	mods := append([]models.ModConfig{}, packs[i].Mods...)
+	if err := saveEnabledMods(mods); err != nil {
+		fail(c, "应用失败: "+err.Error())
+		return
+	}
+	success(c, diff)
+}
@@ -2,14 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"arsm/config"
 	"arsm/models"
 	"github.com/gin-gonic/gin"
 )
 
+// 模组库的读-改-写需持有该锁，避免后台扫描和接口并发修改时丢失改动
+var libraryMu sync.Mutex
+
 // 本地模组库文件（存储所有添加过的模组信息）
 func getLocalModsLibraryPath() string {
 	cfg := config.Get()
@@ -35,7 +42,7 @@ func saveLibraryMods(mods []models.Mod) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, 0644)
 }
 
 // 从 config.json 加载当前启用的模组
@@ -52,7 +59,7 @@ func loadEnabledMods() ([]models.ModConfig, error) {
 	return serverConfig.Game.Mods, nil
 }
 
-// 保存启用模组到 config.json
+// 保存启用模组到 config.json，调用方需持有 configMu 并在读取启用模组前加锁
 func saveEnabledMods(modConfigs []models.ModConfig) error {
 	configPath := getConfigPath()
 	data, err := os.ReadFile(configPath)
@@ -63,43 +70,70 @@ func saveEnabledMods(modConfigs []models.ModConfig) error {
 	if err := json.Unmarshal(data, &serverConfig); err != nil {
 		return err
 	}
+	// 被替换前后的模组都记录最后启用时间
+	touchLastEnabled(serverConfig.Game.Mods, modConfigs)
 	serverConfig.Game.Mods = modConfigs
 	newData, _ := json.MarshalIndent(serverConfig, "", "  ")
-	return os.WriteFile(configPath, newData, 0644)
+	if err := writeFileAtomic(configPath, newData, 0644); err != nil {
+		return err
+	}
+	// 记住加载顺序，禁用后再启用时可恢复原位置；config.json 已写入，失败只输出日志
+	if err := rememberModOrder(modConfigs); err != nil {
+		fmt.Printf("[ARSM] 保存模组加载顺序失败: %v\n", err)
+	}
+	return nil
+}
+
+// touchLastEnabled 更新模组库中这些模组的最后启用时间
+func touchLastEnabled(lists ...[]models.ModConfig) {
+	ids := make(map[string]bool)
+	for _, list := range lists {
+		for _, m := range list {
+			ids[m.ModID] = true
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	libMods, _ := loadLibraryMods()
+	now := time.Now().Unix()
+	changed := false
+	for i := range libMods {
+		if ids[libMods[i].ID] {
+			libMods[i].LastEnabledAt = now
+			changed = true
+		}
+	}
+	if changed {
+		saveLibraryMods(libMods)
+	}
 }
 
 // GetMods 获取模组列表 (合并 Library 和 Config)
+// 支持查询参数 q、tag、enabled、downloaded 过滤
 func GetMods(c *gin.Context) {
 	libMods, _ := loadLibraryMods()
 	enabledMods, _ := loadEnabledMods()
 
-	// 构建启用模组的 Map 用于快速查找
-	enabledMap := make(map[string]bool)
+	// 构建启用模组的 Map 用于快速查找（值为 config.json 中锁定的版本）
+	enabledMap := make(map[string]string)
 	for _, m := range enabledMods {
-		enabledMap[m.ModID] = true
+		enabledMap[m.ModID] = m.Version
 	}
 
-	// 检查本地文件状态
-	cfg := config.Get()
-	addonsDir := filepath.Join(cfg.ServerPath, "addons")
-
+	views := make([]models.ModView, 0, len(libMods))
 	for i := range libMods {
 		// 标记启用状态
-		if enabledMap[libMods[i].ID] {
-			libMods[i].Enabled = true
-		} else {
-			libMods[i].Enabled = false
-		}
-		// 检查是否下载
-		modPath := filepath.Join(addonsDir, libMods[i].ID)
-		if _, err := os.Stat(modPath); err == nil {
-			libMods[i].Downloaded = true
-		} else {
-			libMods[i].Downloaded = false
-		}
+		pinned, enabled := enabledMap[libMods[i].ID]
+		libMods[i].Enabled = enabled
+		// 检查本地文件状态和版本（磁盘版本变更由 scanAddons 记录）
+		views = append(views, modView(libMods[i], pinned))
 	}
 
-	success(c, libMods)
+	success(c, filterMods(c, views))
 }
 
 // AddMod 添加模组到 Library
@@ -110,6 +144,8 @@ func AddMod(c *gin.Context) {
 		return
 	}
 
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
 	libMods, _ := loadLibraryMods()
 
 	// 检查是否已存在
@@ -120,11 +156,25 @@ func AddMod(c *gin.Context) {
 		}
 	}
 
-	// 初始化状态
-	mod.Enabled = false
-	mod.Downloaded = false
+	// 初始化状态，只保留模组库字段
+	mod = models.Mod{
+		ID:           mod.ID,
+		Name:         mod.Name,
+		Version:      mod.Version,
+		Dependencies: mod.Dependencies,
+		Notes:        mod.Notes,
+		RequestedBy:  mod.RequestedBy,
+		Tags:         normalizeTags(mod.Tags),
+		AddedBy:      currentUsername(c),
+		AddedAt:      time.Now().Unix(),
+	}
 	libMods = append(libMods, mod)
 
+	if _, err := newModResolver(libMods).closure(mod.ID); err != nil {
+		fail(c, err.Error())
+		return
+	}
+
 	if err := saveLibraryMods(libMods); err != nil {
 		fail(c, "保存失败")
 		return
@@ -136,8 +186,8 @@ func AddMod(c *gin.Context) {
 // DeleteMod 从 Library 和 Config 中删除模组
 func DeleteMod(c *gin.Context) {
 	id := c.Param("id")
+	libraryMu.Lock()
 	libMods, _ := loadLibraryMods()
-
 	var newLibMods []models.Mod
 	for _, m := range libMods {
 		if m.ID != id {
@@ -145,8 +195,12 @@ func DeleteMod(c *gin.Context) {
 		}
 	}
 	saveLibraryMods(newLibMods)
+	// saveEnabledMods 会更新模组库中的启用时间，需先释放锁
+	libraryMu.Unlock()
 
 	// 同时从 config.json 移除
+	configMu.Lock()
+	defer configMu.Unlock()
 	enabledMods, _ := loadEnabledMods()
 	var newEnabledMods []models.ModConfig
 	for _, m := range enabledMods {
@@ -155,52 +209,82 @@ func DeleteMod(c *gin.Context) {
 		}
 	}
 	saveEnabledMods(newEnabledMods)
+	if err := forgetModOrder(id); err != nil {
+		fmt.Printf("[ARSM] 保存模组加载顺序失败: %v\n", err)
+	}
 
 	success(c, nil)
 }
 
-// EnableMod 启用模组 (添加到 config.json)
+// EnableMod 启用模组 (连同依赖一起添加到 config.json)
 func EnableMod(c *gin.Context) {
 	id := c.Param("id")
-	// 从 Library 获取模组信息
 	libMods, _ := loadLibraryMods()
-	var targetMod *models.Mod
-	for i := range libMods {
-		if libMods[i].ID == id {
-			targetMod = &libMods[i]
-			break
+	resolver := newModResolver(libMods)
+	if _, ok := resolver.lib[id]; !ok {
+		fail(c, "模组不存在")
+		return
+	}
+
+	// 计算需要启用的全部模组（依赖在前）
+	closure, err := resolver.closure(id)
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+	var missing []string
+	for _, dep := range closure {
+		if _, ok := resolver.lib[dep]; !ok {
+			missing = append(missing, dep)
 		}
 	}
-	if targetMod == nil {
-		fail(c, "模组不存在")
+	if len(missing) > 0 {
+		fail(c, "缺少依赖模组，请先添加到模组库: "+strings.Join(missing, ", "))
 		return
 	}
 
+	configMu.Lock()
+	defer configMu.Unlock()
 	enabledMods, _ := loadEnabledMods()
-	// 检查是否已启用
+	enabledSet := make(map[string]bool)
 	for _, m := range enabledMods {
-		if m.ModID == id {
-			success(c, nil)
-			return
-		}
+		enabledSet[m.ModID] = true
 	}
 
 	// 添加到 config.json，使用 Library 中存储的 version
-	enabledMods = append(enabledMods, models.ModConfig{
-		ModID:   targetMod.ID,
-		Name:    targetMod.Name,
-		Version: targetMod.Version,
-	})
+	// 按记住的加载顺序插入，并保证依赖排在前面
+	remembered := loadModOrder()
+	added := []string{}
+	for _, modID := range closure {
+		if enabledSet[modID] {
+			continue
+		}
+		mod := resolver.lib[modID]
+		enabledMods = insertEnabledMod(enabledMods, models.ModConfig{
+			ModID:   mod.ID,
+			Name:    mod.Name,
+			Version: mod.Version,
+		}, remembered)
+		enabledMods = ensureAfterDependencies(enabledMods, modID, resolver)
+		added = append(added, modID)
+	}
+	if len(added) == 0 {
+		success(c, gin.H{"enabled": added})
+		return
+	}
+
 	if err := saveEnabledMods(enabledMods); err != nil {
 		fail(c, "启用失败: "+err.Error())
 		return
 	}
-	success(c, nil)
+	success(c, gin.H{"enabled": added})
 }
 
 // DisableMod 禁用模组 (从 config.json 移除)
 func DisableMod(c *gin.Context) {
 	id := c.Param("id")
+	configMu.Lock()
+	defer configMu.Unlock()
 	enabledMods, _ := loadEnabledMods()
 	var newEnabledMods []models.ModConfig
 	for _, m := range enabledMods {
@@ -212,17 +296,27 @@ func DisableMod(c *gin.Context) {
 		fail(c, "禁用失败: "+err.Error())
 		return
 	}
-	success(c, nil)
+
+	// 仍启用的模组依赖于该模组时给出警告
+	libMods, _ := loadLibraryMods()
+	resolver := newModResolver(libMods)
+	warnings := []string{}
+	for _, dependent := range resolver.dependents(id, newEnabledMods) {
+		warnings = append(warnings, fmt.Sprintf("%s 依赖于 %s", resolver.modLabel(dependent), resolver.modLabel(id)))
+	}
+	success(c, gin.H{"warnings": warnings})
 }
 
 // CheckModFiles 检查模组文件
 func CheckModFiles(c *gin.Context) {
 	id := c.Param("id")
-	cfg := config.Get()
-	modPath := filepath.Join(cfg.ServerPath, "addons", id)
-	if _, err := os.Stat(modPath); err == nil {
-		success(c, map[string]bool{"downloaded": true})
-	} else {
-		success(c, map[string]bool{"downloaded": false})
+	addon := inspectAddon(id)
+	if addon == nil {
+		success(c, map[string]interface{}{"downloaded": false})
+		return
 	}
+	success(c, map[string]interface{}{
+		"downloaded": true,
+		"addon":      addon,
+	})
 }
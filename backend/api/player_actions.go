@@ -0,0 +1,333 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultKickReason = "Kicked by Admin"
+
+// rePlayerID 在线玩家的会话编号（players 列表中的 #编号）
+var rePlayerID = regexp.MustCompile(`^\d+$`)
+
+// normalizePlayerID 去掉空白和 # 前缀并校验玩家编号，避免把其他内容拼进 RCON 命令
+func normalizePlayerID(id string) (string, error) {
+	id = strings.TrimPrefix(strings.TrimSpace(id), "#")
+	if !rePlayerID.MatchString(id) {
+		return "", fmt.Errorf("无效的玩家编号: %s", id)
+	}
+	return id, nil
+}
+
+// 原因模板文件
+func getReasonTemplatesPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_reason_templates.json")
+}
+
+func loadReasonTemplates() ([]models.ReasonTemplate, error) {
+	data, err := os.ReadFile(getReasonTemplatesPath())
+	if err != nil {
+		return []models.ReasonTemplate{}, nil
+	}
+	var templates []models.ReasonTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return []models.ReasonTemplate{}, nil
+	}
+	return templates, nil
+}
+
+func saveReasonTemplates(templates []models.ReasonTemplate) error {
+	data, err := json.MarshalIndent(templates, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getReasonTemplatesPath(), data, 0644)
+}
+
+func findReasonTemplate(templates []models.ReasonTemplate, name string) int {
+	for i := range templates {
+		if templates[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveReason 确定操作原因：优先使用 reason，否则使用模板；同时返回模板的默认封禁时长
+func resolveReason(reason, template, action string) (string, int, error) {
+	if template == "" {
+		return reason, 0, nil
+	}
+	templates, _ := loadReasonTemplates()
+	i := findReasonTemplate(templates, template)
+	if i < 0 {
+		return "", 0, fmt.Errorf("原因模板不存在: %s", template)
+	}
+	t := templates[i]
+	if t.Action != "" && t.Action != action {
+		return "", 0, fmt.Errorf("原因模板 %s 仅用于 %s", t.Name, t.Action)
+	}
+	if strings.TrimSpace(reason) == "" {
+		reason = t.Reason
+	}
+	return reason, t.Duration, nil
+}
+
+// kickCommand 构造踢出命令，原因为空时使用默认原因
+func kickCommand(id, reason string) string {
+	reason = strings.Join(strings.Fields(reason), " ")
+	if reason == "" {
+		reason = defaultKickReason
+	}
+	return fmt.Sprintf("kick %s %s", id, reason)
+}
+
+// execPlayerCommand 以指定用户身份执行玩家操作命令
+func execPlayerCommand(user, command string) error {
+	_, err := rconExecAs(user, command)
+	return err
+}
+
+// kickPlayer 踢出在线玩家
+func kickPlayer(user, id, reason string) error {
+	return execPlayerCommand(user, kickCommand(id, reason))
+}
+
+// messageCommand 构造私信命令，id 为 -1 时发送给所有玩家
+func messageCommand(id, message string) (string, error) {
+	message = strings.Join(strings.Fields(message), " ")
+	if message == "" {
+		return "", fmt.Errorf("消息内容不能为空")
+	}
+	return fmt.Sprintf("say %s %s", id, message), nil
+}
+
+// messagePlayer 向单个玩家发送私信
+func messagePlayer(user, id, message string) error {
+	command, err := messageCommand(id, message)
+	if err != nil {
+		return err
+	}
+	return execPlayerCommand(user, command)
+}
+
+// GetReasonTemplates 获取原因模板列表
+func GetReasonTemplates(c *gin.Context) {
+	templates, _ := loadReasonTemplates()
+	action := c.Query("action")
+	if action == "" {
+		success(c, templates)
+		return
+	}
+	result := []models.ReasonTemplate{}
+	for _, t := range templates {
+		if t.Action == "" || t.Action == action {
+			result = append(result, t)
+		}
+	}
+	success(c, result)
+}
+
+// validateReasonTemplate 检查模板字段
+func validateReasonTemplate(t *models.ReasonTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Reason = strings.Join(strings.Fields(t.Reason), " ")
+	if t.Name == "" || t.Reason == "" {
+		return fmt.Errorf("名称和原因不能为空")
+	}
+	if t.Action != "" && t.Action != "kick" && t.Action != "ban" {
+		return fmt.Errorf("action 只能是 kick 或 ban")
+	}
+	if t.Duration < 0 {
+		return fmt.Errorf("封禁时长不能为负数")
+	}
+	return nil
+}
+
+// CreateReasonTemplate 新建原因模板
+func CreateReasonTemplate(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var t models.ReasonTemplate
+	if err := c.ShouldBindJSON(&t); err != nil {
+		fail(c, "无效的模板数据")
+		return
+	}
+	if err := validateReasonTemplate(&t); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	templates, _ := loadReasonTemplates()
+	if findReasonTemplate(templates, t.Name) >= 0 {
+		fail(c, "模板名称已存在")
+		return
+	}
+	templates = append(templates, t)
+	if err := saveReasonTemplates(templates); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, t)
+}
+
+// UpdateReasonTemplate 修改原因模板
+func UpdateReasonTemplate(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var t models.ReasonTemplate
+	if err := c.ShouldBindJSON(&t); err != nil {
+		fail(c, "无效的模板数据")
+		return
+	}
+	name := c.Param("name")
+	if t.Name == "" {
+		t.Name = name
+	}
+	if err := validateReasonTemplate(&t); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	templates, _ := loadReasonTemplates()
+	i := findReasonTemplate(templates, name)
+	if i < 0 {
+		fail(c, "模板不存在")
+		return
+	}
+	if t.Name != name && findReasonTemplate(templates, t.Name) >= 0 {
+		fail(c, "模板名称已存在")
+		return
+	}
+	templates[i] = t
+	if err := saveReasonTemplates(templates); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, t)
+}
+
+// DeleteReasonTemplate 删除原因模板
+func DeleteReasonTemplate(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	templates, _ := loadReasonTemplates()
+	i := findReasonTemplate(templates, c.Param("name"))
+	if i < 0 {
+		fail(c, "模板不存在")
+		return
+	}
+	templates = append(templates[:i], templates[i+1:]...)
+	if err := saveReasonTemplates(templates); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// BulkPlayerAction 对多个玩家执行踢出 / 封禁 / 私信，逐个返回结果
+func BulkPlayerAction(c *gin.Context) {
+	var req struct {
+		Action    string   `json:"action"` // kick / ban / message
+		PlayerIDs []string `json:"player_ids"`
+		Reason    string   `json:"reason"`
+		Template  string   `json:"template"`
+		Duration  *int     `json:"duration"`
+		Message   string   `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, "无效的请求数据")
+		return
+	}
+	if len(req.PlayerIDs) == 0 {
+		fail(c, "请选择玩家")
+		return
+	}
+
+	var reason string
+	switch req.Action {
+	case "kick":
+		var err error
+		if reason, _, err = resolveReason(req.Reason, req.Template, "kick"); err != nil {
+			fail(c, err.Error())
+			return
+		}
+	case "ban":
+		// 模板在 issueBan 中解析
+		if _, _, err := resolveReason(req.Reason, req.Template, "ban"); err != nil {
+			fail(c, err.Error())
+			return
+		}
+	case "message":
+		if strings.TrimSpace(req.Message) == "" {
+			fail(c, "消息内容不能为空")
+			return
+		}
+	default:
+		fail(c, "action 只能是 kick、ban 或 message")
+		return
+	}
+
+	// 先取一次玩家列表，用于返回名称
+	names := make(map[string]string)
+	if resp, err := rconExec("players"); err == nil {
+		for _, p := range parsePlayerList(resp) {
+			names[p.ID] = p.Name
+		}
+	}
+
+	issuer := currentUsername(c)
+	results := make([]models.PlayerActionResult, 0, len(req.PlayerIDs))
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(req.PlayerIDs))
+	for _, raw := range req.PlayerIDs {
+		id, err := normalizePlayerID(raw)
+		if err != nil {
+			fail(c, err.Error())
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		result := models.PlayerActionResult{PlayerID: id, Name: names[id]}
+		var err error
+		switch req.Action {
+		case "kick":
+			command := kickCommand(id, reason)
+			if err = checkRCONPolicy(c, command); err == nil {
+				err = execPlayerCommand(issuer, command)
+			}
+		case "ban":
+			var ban models.Ban
+			ban, _, err = issueBan(banRequest{PlayerID: id, Duration: req.Duration, Reason: req.Reason, Template: req.Template}, issuer, rconPolicyChecker(c))
+			result.BanID = ban.ID
+		case "message":
+			var command string
+			if command, err = messageCommand(id, req.Message); err == nil {
+				if err = checkRCONPolicy(c, command); err == nil {
+					err = execPlayerCommand(issuer, command)
+				}
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+		results = append(results, result)
+	}
+	success(c, results)
+}
@@ -0,0 +1,624 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/models"
+	"arsm/store"
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 玩家历史数据库中的 bucket
+const (
+	bucketPlayers        = "players"         // GUID -> PlayerRecord
+	bucketPlayerSessions = "player_sessions" // 会话序号 -> PlayerSession
+)
+
+const (
+	playerPollInterval = 30 * time.Second
+	// 每个会话保留的延迟采样数
+	maxPingSamples = 120
+	// 连续多少次无法获取玩家列表后认为服务端已停止，结束所有会话
+	playerOfflineAfterMisses = 3
+)
+
+// playerTracker 根据玩家列表和服务端消息维护在线会话
+type playerTracker struct {
+	mu      sync.Mutex
+	active  map[string]*models.PlayerSession // 按服务端会话编号
+	misses  int
+	pending []sessionWrite // 本轮待保存的会话变化，由 flush 在一个事务中写入
+}
+
+// sessionWrite 一次待保存的会话变化
+type sessionWrite struct {
+	sess        *models.PlayerSession
+	now         int64
+	newSession  bool
+	addDuration int64
+}
+
+var playerSessions = &playerTracker{active: make(map[string]*models.PlayerSession)}
+
+func init() {
+	onRCONMessage(playerSessions.onMessage)
+}
+
+// saveSession 写入会话，新会话分配序号
+func saveSession(tx *bolt.Tx, sess *models.PlayerSession) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(bucketPlayerSessions))
+	if err != nil {
+		return err
+	}
+	if sess.ID == 0 {
+		if sess.ID, err = b.NextSequence(); err != nil {
+			return err
+		}
+	}
+	end := sess.LeftAt
+	if end == 0 {
+		end = sess.LastSeen
+	}
+	sess.Duration = end - sess.JoinedAt
+	return store.PutJSON(b, store.Itob(sess.ID), sess)
+}
+
+// touchAlias 更新名称或 IP 的使用记录
+func touchAlias(aliases []models.PlayerAlias, value string, now int64, newSession bool) []models.PlayerAlias {
+	if value == "" {
+		return aliases
+	}
+	for i := range aliases {
+		if aliases[i].Value == value {
+			aliases[i].LastSeen = now
+			if newSession {
+				aliases[i].Count++
+			}
+			return aliases
+		}
+	}
+	return append(aliases, models.PlayerAlias{Value: value, FirstSeen: now, LastSeen: now, Count: 1})
+}
+
+// touchPlayerRecord 将会话信息汇总到玩家记录，addDuration 为会话结束时累计的在线时长
+func touchPlayerRecord(tx *bolt.Tx, sess *models.PlayerSession, now int64, newSession bool, addDuration int64) error {
+	if sess.GUID == "" {
+		return nil
+	}
+	b, err := tx.CreateBucketIfNotExists([]byte(bucketPlayers))
+	if err != nil {
+		return err
+	}
+	var rec models.PlayerRecord
+	found, err := store.GetJSON(b, []byte(sess.GUID), &rec)
+	if err != nil {
+		return err
+	}
+	if !found {
+		rec = models.PlayerRecord{GUID: sess.GUID, FirstSeen: sess.JoinedAt}
+	}
+	rec.Name = sess.Name
+	rec.Names = touchAlias(rec.Names, sess.Name, now, newSession)
+	rec.IPs = touchAlias(rec.IPs, sess.IP, now, newSession)
+	rec.LastSeen = now
+	if newSession {
+		rec.SessionCount++
+	}
+	rec.TotalOnline += addDuration
+	rec.Online = false // 在线状态由内存中的会话计算，不持久化
+	return store.PutJSON(b, []byte(rec.GUID), rec)
+}
+
+// open 开始新会话（调用方持有锁）
+func (t *playerTracker) open(s models.Player, now int64) {
+	sess := &models.PlayerSession{
+		GUID:     playerIdentity(s),
+		PlayerID: s.ID,
+		Name:     s.Name,
+		IP:       s.IP,
+		Port:     s.Port,
+		JoinedAt: now,
+		LastSeen: now,
+	}
+	if s.Ping > 0 {
+		t.addPing(sess, s.Ping, now)
+	}
+	t.active[s.ID] = sess
+	t.persist(sess, now, true, 0)
+}
+
+// close 结束会话（调用方持有锁）
+func (t *playerTracker) close(sess *models.PlayerSession, at int64) {
+	if at < sess.LastSeen {
+		at = sess.LastSeen
+	}
+	sess.LeftAt = at
+	sess.LastSeen = at
+	delete(t.active, sess.PlayerID)
+	t.persist(sess, at, false, at-sess.JoinedAt)
+}
+
+// persist 记录需要保存的会话和玩家记录变化（调用方持有锁），由 flush 统一写入
+func (t *playerTracker) persist(sess *models.PlayerSession, now int64, newSession bool, addDuration int64) {
+	t.pending = append(t.pending, sessionWrite{sess: sess, now: now, newSession: newSession, addDuration: addDuration})
+}
+
+// flush 在一个事务中保存本轮的全部会话变化（调用方持有锁，会话序号需写回内存中的会话）
+func (t *playerTracker) flush() {
+	if len(t.pending) == 0 {
+		return
+	}
+	writes := t.pending
+	t.pending = nil
+	err := store.Update(func(tx *bolt.Tx) error {
+		for _, w := range writes {
+			if err := saveSession(tx, w.sess); err != nil {
+				return err
+			}
+			if err := touchPlayerRecord(tx, w.sess, w.now, w.newSession, w.addDuration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("[ARSM] 保存玩家会话失败: %v\n", err)
+	}
+}
+
+// addPing 记录延迟采样并更新平均值和最大值
+func (t *playerTracker) addPing(sess *models.PlayerSession, ping int, now int64) {
+	sess.PingSamples = append(sess.PingSamples, models.PingSample{Time: now, Ping: ping})
+	if len(sess.PingSamples) > maxPingSamples {
+		sess.PingSamples = sess.PingSamples[len(sess.PingSamples)-maxPingSamples:]
+	}
+	total := 0
+	for _, p := range sess.PingSamples {
+		total += p.Ping
+	}
+	sess.PingAvg = total / len(sess.PingSamples)
+	if ping > sess.PingMax {
+		sess.PingMax = ping
+	}
+}
+
+// observe 用最新的玩家列表对齐在线会话
+func (t *playerTracker) observe(list []models.Player) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.flush()
+
+	now := time.Now().Unix()
+	t.misses = 0
+	seen := make(map[string]bool, len(list))
+	for _, s := range list {
+		seen[s.ID] = true
+		sess := t.active[s.ID]
+		identity := playerIdentity(s)
+		// 同一编号换了人：结束旧会话
+		if sess != nil && (sess.Name != s.Name || (sess.GUID != "" && identity != "" && sess.GUID != identity)) {
+			t.close(sess, sess.LastSeen)
+			sess = nil
+		}
+		if sess == nil {
+			t.open(s, now)
+			continue
+		}
+
+		learnedGUID := sess.GUID == "" && identity != ""
+		if learnedGUID {
+			sess.GUID = identity
+		}
+		if sess.IP == "" {
+			sess.IP = s.IP
+		}
+		if sess.Port == 0 {
+			sess.Port = s.Port
+		}
+		sess.LastSeen = now
+		if s.Ping > 0 {
+			t.addPing(sess, s.Ping, now)
+		}
+		t.persist(sess, now, learnedGUID, 0)
+	}
+	for id, sess := range t.active {
+		if !seen[id] {
+			t.close(sess, now)
+		}
+	}
+}
+
+// markUnavailable 无法获取玩家列表，连续多次后结束所有会话
+func (t *playerTracker) markUnavailable() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.flush()
+
+	t.misses++
+	if t.misses < playerOfflineAfterMisses {
+		return
+	}
+	for _, sess := range t.active {
+		t.close(sess, sess.LastSeen)
+	}
+}
+
+// onMessage 根据连接、GUID、断开消息及时更新会话
+func (t *playerTracker) onMessage(msg models.RCONMessage) {
+	if msg.PlayerID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.flush()
+
+	sess := t.active[msg.PlayerID]
+	switch msg.Type {
+	case rconMsgConnect:
+		if sess != nil {
+			t.close(sess, msg.Time)
+		}
+		t.open(models.Player{ID: msg.PlayerID, Name: msg.PlayerName, IP: msg.IP}, msg.Time)
+	case rconMsgGUID:
+		if sess != nil && sess.GUID == "" && sess.Name == msg.PlayerName {
+			sess.GUID = msg.GUID
+			t.persist(sess, msg.Time, true, 0)
+		}
+	case rconMsgDisconnect, rconMsgKick:
+		if sess != nil && sess.Name == msg.PlayerName {
+			t.close(sess, msg.Time)
+		}
+	}
+}
+
+// onlineSince 返回会话编号对应玩家的上线时间
+func (t *playerTracker) onlineSince(id string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if sess := t.active[id]; sess != nil {
+		return sess.JoinedAt
+	}
+	return 0
+}
+
+// count 当前在线人数
+func (t *playerTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// onlineByGUID 返回在线玩家的当前会话
+func (t *playerTracker) onlineByGUID() map[string]models.PlayerSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make(map[string]models.PlayerSession)
+	for _, sess := range t.active {
+		if sess.GUID != "" {
+			result[sess.GUID] = *sess
+		}
+	}
+	return result
+}
+
+// closeDanglingSessions ARSM 上次退出时未结束的会话，以最后一次看到的时间结束
+func closeDanglingSessions() error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketPlayerSessions))
+		if err != nil {
+			return err
+		}
+		var dangling []models.PlayerSession
+		err = b.ForEach(func(k, v []byte) error {
+			var sess models.PlayerSession
+			if err := json.Unmarshal(v, &sess); err != nil {
+				return err
+			}
+			if sess.LeftAt == 0 {
+				dangling = append(dangling, sess)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, sess := range dangling {
+			sess.LeftAt = sess.LastSeen
+			if err := saveSession(tx, &sess); err != nil {
+				return err
+			}
+			if err := touchPlayerRecord(tx, &sess, sess.LeftAt, false, sess.Duration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pollPlayers 获取一次玩家列表并更新会话
+func pollPlayers() {
+	if !rconManager.Connected() {
+		playerSessions.markUnavailable()
+		return
+	}
+	resp, err := rconExec("players")
+	if err != nil {
+		playerSessions.markUnavailable()
+		return
+	}
+	players := parsePlayerList(resp)
+	playerSessions.observe(players)
+	runModeration(players, time.Now())
+}
+
+// StartPlayerTracker 定期记录玩家会话
+func StartPlayerTracker() {
+	if err := closeDanglingSessions(); err != nil {
+		fmt.Printf("[ARSM] ⚠️ 玩家历史数据库不可用: %v\n", err)
+	}
+	go func() {
+		for {
+			time.Sleep(playerPollInterval)
+			pollPlayers()
+		}
+	}()
+}
+
+// withLiveStatus 叠加当前会话的在线状态和时长
+func withLiveStatus(rec models.PlayerRecord, online map[string]models.PlayerSession, now int64) models.PlayerRecord {
+	if sess, ok := online[rec.GUID]; ok {
+		rec.Online = true
+		rec.TotalOnline += now - sess.JoinedAt
+	}
+	return rec
+}
+
+// loadPlayerRecords 读取全部玩家记录
+func loadPlayerRecords() ([]models.PlayerRecord, error) {
+	records := []models.PlayerRecord{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketPlayers))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var rec models.PlayerRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// loadSessions 从新到旧读取会话，guid 为空时不过滤；before 为 0 时从最新开始
+func loadSessions(guid string, before uint64, limit int) ([]models.PlayerSession, error) {
+	sessions := []models.PlayerSession{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketPlayerSessions))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		k, v := c.Last()
+		if before > 0 {
+			if sk, _ := c.Seek(store.Itob(before)); sk != nil {
+				k, v = c.Prev()
+			}
+		}
+		for ; k != nil && len(sessions) < limit; k, v = c.Prev() {
+			var sess models.PlayerSession
+			if err := json.Unmarshal(v, &sess); err != nil {
+				return err
+			}
+			if guid != "" && !strings.EqualFold(sess.GUID, guid) {
+				continue
+			}
+			sessions = append(sessions, sess)
+		}
+		return nil
+	})
+	return sessions, err
+}
+
+// maskPlayerRecord 非管理员只能看到部分 IP
+func maskPlayerRecord(rec models.PlayerRecord) models.PlayerRecord {
+	ips := make([]models.PlayerAlias, len(rec.IPs))
+	for i, a := range rec.IPs {
+		a.Value = maskIP(a.Value)
+		ips[i] = a
+	}
+	rec.IPs = ips
+	return rec
+}
+
+// maskSessions 隐藏会话中的 IP 和端口
+func maskSessions(sessions []models.PlayerSession) {
+	for i := range sessions {
+		sessions[i].IP, sessions[i].Port = maskIP(sessions[i].IP), 0
+	}
+}
+
+// matchPlayer 按名称（含曾用名）、GUID 或 IP 模糊匹配，matchIP 为 false 时不匹配 IP
+func matchPlayer(rec models.PlayerRecord, q string, matchIP bool) bool {
+	if strings.Contains(strings.ToLower(rec.GUID), q) {
+		return true
+	}
+	for _, a := range rec.Names {
+		if strings.Contains(strings.ToLower(a.Value), q) {
+			return true
+		}
+	}
+	if !matchIP {
+		return false
+	}
+	for _, a := range rec.IPs {
+		if strings.Contains(a.Value, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryInt 读取正整数查询参数，缺省或超出范围时使用默认值
+func queryInt(c *gin.Context, key string, def, max int) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil || v <= 0 || v > max {
+		return def
+	}
+	return v
+}
+
+// GetPlayerHistory 搜索玩家历史（q 匹配名称、曾用名、GUID、IP）
+func GetPlayerHistory(c *gin.Context) {
+	records, err := loadPlayerRecords()
+	if err != nil {
+		fail(c, "读取玩家历史失败: "+err.Error())
+		return
+	}
+
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	onlineOnly := c.Query("online") == "true"
+	online := playerSessions.onlineByGUID()
+	now := time.Now().Unix()
+	admin := isAdmin(c)
+
+	filtered := []models.PlayerRecord{}
+	for _, rec := range records {
+		rec = withLiveStatus(rec, online, now)
+		if onlineOnly && !rec.Online {
+			continue
+		}
+		if q != "" && !matchPlayer(rec, q, admin) {
+			continue
+		}
+		if !admin {
+			rec = maskPlayerRecord(rec)
+		}
+		filtered = append(filtered, rec)
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		if filtered[i].Online != filtered[j].Online {
+			return filtered[i].Online
+		}
+		return filtered[i].LastSeen > filtered[j].LastSeen
+	})
+
+	total := len(filtered)
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 || offset > total {
+		offset = total
+	}
+	limit := queryInt(c, "limit", 50, 500)
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	success(c, gin.H{"total": total, "players": filtered[offset:end]})
+}
+
+// GetPlayerRecord 获取单个玩家的汇总信息和最近会话
+func GetPlayerRecord(c *gin.Context) {
+	guid := c.Param("guid")
+	var rec models.PlayerRecord
+	var found bool
+	err := store.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = store.GetJSON(tx.Bucket([]byte(bucketPlayers)), []byte(guid), &rec)
+		return err
+	})
+	if err != nil {
+		fail(c, "读取玩家历史失败: "+err.Error())
+		return
+	}
+	if !found {
+		fail(c, "未找到该玩家")
+		return
+	}
+
+	sessions, err := loadSessions(guid, 0, 20)
+	if err != nil {
+		fail(c, "读取会话失败: "+err.Error())
+		return
+	}
+	rec = withLiveStatus(rec, playerSessions.onlineByGUID(), time.Now().Unix())
+	if !isAdmin(c) {
+		rec = maskPlayerRecord(rec)
+		maskSessions(sessions)
+	}
+	success(c, gin.H{"player": rec, "sessions": sessions})
+}
+
+// GetPlayerSessions 获取会话历史，可按 GUID 过滤；before 为上一页最后一条的 ID
+func GetPlayerSessions(c *gin.Context) {
+	guid := c.Param("guid")
+	if guid == "" {
+		guid = c.Query("guid")
+	}
+	before, _ := strconv.ParseUint(c.Query("before"), 10, 64)
+	sessions, err := loadSessions(guid, before, queryInt(c, "limit", 50, 500))
+	if err != nil {
+		fail(c, "读取会话失败: "+err.Error())
+		return
+	}
+	if !isAdmin(c) {
+		maskSessions(sessions)
+	}
+	success(c, sessions)
+}
+
+// GetPlayerAliases 查找使用过某名称或 IP 的所有玩家（账号关联）
+func GetPlayerAliases(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	ip := strings.TrimSpace(c.Query("ip"))
+	if name == "" && ip == "" {
+		fail(c, "请提供 name 或 ip")
+		return
+	}
+	admin := isAdmin(c)
+	if ip != "" && !admin {
+		fail(c, "只有管理员可以按 IP 查询")
+		return
+	}
+
+	records, err := loadPlayerRecords()
+	if err != nil {
+		fail(c, "读取玩家历史失败: "+err.Error())
+		return
+	}
+	online := playerSessions.onlineByGUID()
+	now := time.Now().Unix()
+
+	result := []models.PlayerRecord{}
+	for _, rec := range records {
+		matched := false
+		for _, a := range rec.Names {
+			if name != "" && strings.EqualFold(a.Value, name) {
+				matched = true
+			}
+		}
+		for _, a := range rec.IPs {
+			if ip != "" && a.Value == ip {
+				matched = true
+			}
+		}
+		if matched {
+			rec = withLiveStatus(rec, online, now)
+			if !admin {
+				rec = maskPlayerRecord(rec)
+			}
+			result = append(result, rec)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen > result[j].LastSeen })
+	success(c, result)
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"arsm/models"
+)
+
+// players 命令输出随服务端版本不同有多种格式，已见过的样例：
+//
+// 旧版 ARSM 适配的格式：
+//
+//	#0 111.111.111.111:12345 <ping:123> <guid:abc123...> PlayerName
+//	(1 players in total)
+//
+// BattlEye 表格格式（GUID 后可能带 (OK) / (?)，名称后可能带 (Lobby)）：
+//
+//	Players on server:
+//	[#] [IP Address]:[Port] [Ping] [GUID] [Name]
+//	--------------------------------------------------
+//	0   111.111.111.111:2304  45   0123456789abcdef0123456789abcdef(OK) PlayerName
+//	(1 players in total)
+//
+// Reforger #players 分隔格式（字段顺序不固定，可带 key: value 前缀）：
+//
+//	1;PlayerName;5b0e4e6c-9d2f-4b7c-8c7e-1234567890ab;76561198000000000
+//	ID: 1 | Name: PlayerName | IdentityId: 5b0e4e6c-... | Ping: 40
+var (
+	rePlayerTagged = regexp.MustCompile(`^#(\d+)\s+(\S+)\s+<ping:(\d+)>\s+<guid:([0-9A-Za-z-]+)>\s*(.+)$`)
+	rePlayerTable  = regexp.MustCompile(`^#?(\d+)\s+(\S+:\d+)\s+(-?\d+)\s+([0-9A-Za-z-]+)(?:\((?:OK|\?)\))?\s+(.+?)(\s+\(Lobby\))?$`)
+	reHexGUID      = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+	reIdentityID   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	reSteamID      = regexp.MustCompile(`^7656\d{13}$`)
+
+	// 文本中可能出现的 IP：IPv4，或方括号中的 IPv6（避免误伤 12:34:56 这样的时间）
+	reTextIP = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b|\[[0-9A-Fa-f:.]+\]`)
+)
+
+// splitHostPort 拆分 IP:端口，支持 [IPv6]:端口 和不带端口的地址
+func splitHostPort(s string) (string, int, bool) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		host, portStr = strings.Trim(s, "[]"), ""
+	}
+	if net.ParseIP(host) == nil {
+		return "", 0, false
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port, true
+}
+
+// setPlayerIdentity 按格式识别标识类型
+func setPlayerIdentity(p *models.Player, id string) bool {
+	switch {
+	case reIdentityID.MatchString(id):
+		p.IdentityID = strings.ToLower(id)
+	case reSteamID.MatchString(id):
+		p.PlatformID = id
+	case reHexGUID.MatchString(id):
+		p.GUID = strings.ToLower(id)
+	default:
+		return false
+	}
+	return true
+}
+
+// setPlayerGUID 表格中的标识列，未验证时为 "-"
+func setPlayerGUID(p *models.Player, id string) {
+	if id == "" || id == "-" {
+		return
+	}
+	if !setPlayerIdentity(p, id) {
+		p.GUID = strings.ToLower(id)
+	}
+}
+
+// parseDelimitedPlayer 解析 ; | , 或制表符分隔的行，按字段名或字段内容识别含义
+func parseDelimitedPlayer(line string) (models.Player, bool) {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ';' || r == '|' || r == ',' || r == '\t'
+	})
+	if len(fields) < 2 {
+		return models.Player{}, false
+	}
+
+	var p models.Player
+	var rest []string
+	for _, raw := range fields {
+		f := strings.TrimSpace(raw)
+		if f == "" {
+			continue
+		}
+		raw = f
+		key := ""
+		if i := strings.IndexAny(f, ":="); i > 0 && !strings.Contains(f[:i], " ") && net.ParseIP(f) == nil {
+			if _, _, ok := splitHostPort(f); !ok {
+				key, f = strings.ToLower(f[:i]), strings.TrimSpace(f[i+1:])
+			}
+		}
+
+		switch key {
+		case "id", "#", "playerid", "player_id":
+			p.ID = strings.TrimPrefix(f, "#")
+		case "name", "playername":
+			p.Name = f
+		case "guid", "beguid":
+			p.GUID = strings.ToLower(f)
+		case "identity", "identityid", "identity_id", "uid":
+			p.IdentityID = strings.ToLower(f)
+		case "steamid", "platformid", "platform_id", "steam":
+			p.PlatformID = f
+		case "ip", "address":
+			p.IP, p.Port, _ = splitHostPort(f)
+		case "ping":
+			p.Ping, _ = strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(f, "ms")))
+		case "port":
+			p.Port, _ = strconv.Atoi(f)
+		case "":
+			// 无字段名时按内容识别
+			id := strings.TrimPrefix(f, "#")
+			if ip, port, ok := splitHostPort(f); ok && p.IP == "" {
+				p.IP, p.Port = ip, port
+			} else if p.ID == "" && isDigits(id) {
+				p.ID = id
+			} else if !setPlayerIdentity(&p, f) {
+				rest = append(rest, f)
+			}
+		default:
+			// 不认识的字段名，可能是名称中带冒号
+			rest = append(rest, raw)
+		}
+	}
+	// 剩余的第一个字段视为名称
+	if p.Name == "" && len(rest) > 0 {
+		p.Name = rest[0]
+	}
+	if p.ID == "" || p.Name == "" {
+		return models.Player{}, false
+	}
+	return p, true
+}
+
+// isDigits 是否为非空的纯数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePlayerLine 解析一行玩家信息，不是玩家行时返回 false
+func parsePlayerLine(line string) (models.Player, bool) {
+	if m := rePlayerTagged.FindStringSubmatch(line); m != nil {
+		p := models.Player{ID: m[1], Name: strings.TrimSpace(m[5])}
+		p.IP, p.Port, _ = splitHostPort(m[2])
+		p.Ping, _ = strconv.Atoi(m[3])
+		setPlayerGUID(&p, m[4])
+		return p, true
+	}
+	if m := rePlayerTable.FindStringSubmatch(line); m != nil {
+		ip, port, ok := splitHostPort(m[2])
+		if ok {
+			p := models.Player{ID: m[1], IP: ip, Port: port, Name: strings.TrimSpace(m[5]), Lobby: m[6] != ""}
+			// 未完成验证的玩家延迟为 -1
+			if ping, _ := strconv.Atoi(m[3]); ping > 0 {
+				p.Ping = ping
+			}
+			setPlayerGUID(&p, m[4])
+			return p, true
+		}
+	}
+	return parseDelimitedPlayer(line)
+}
+
+// parsePlayerList 解析 players 命令输出，跳过标题、分隔线和统计行
+func parsePlayerList(output string) []models.Player {
+	players := []models.Player{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+		if line == "" || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "[#]") ||
+			strings.Contains(lower, "players in total") || strings.HasPrefix(lower, "players on server") ||
+			strings.HasPrefix(lower, "players:") {
+			continue
+		}
+		if p, ok := parsePlayerLine(line); ok {
+			p.Online = true
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// playerIdentity 玩家的持久标识：优先 BattlEye GUID，其次身份 ID、平台 ID
+func playerIdentity(p models.Player) string {
+	switch {
+	case p.GUID != "":
+		return p.GUID
+	case p.IdentityID != "":
+		return p.IdentityID
+	default:
+		return p.PlatformID
+	}
+}
+
+// maskIP 隐藏 IP 的后半部分，供非管理员查看
+func maskIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return strconv.Itoa(int(v4[0])) + "." + strconv.Itoa(int(v4[1])) + ".x.x"
+	}
+	parts := strings.Split(parsed.String(), ":")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, ":") + ":x"
+}
+
+// maskIPsInText 隐藏文本中出现的所有 IP，用于命令响应、服务端消息等原始文本
+func maskIPsInText(s string) string {
+	return reTextIP.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "[") {
+			if masked := maskIP(strings.Trim(m, "[]")); masked != "" {
+				return "[" + masked + "]"
+			}
+			return m
+		}
+		if masked := maskIP(m); masked != "" {
+			return masked
+		}
+		return m
+	})
+}
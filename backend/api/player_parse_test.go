@@ -0,0 +1,164 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"arsm/models"
+)
+
+func TestParsePlayerLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want models.Player
+		ok   bool
+	}{
+		{
+			name: "tagged",
+			line: "#0 111.111.111.111:12345 <ping:123> <guid:0123456789ABCDEF0123456789ABCDEF> Player One",
+			want: models.Player{ID: "0", Name: "Player One", IP: "111.111.111.111", Port: 12345, Ping: 123, GUID: "0123456789abcdef0123456789abcdef"},
+			ok:   true,
+		},
+		{
+			name: "tagged with identity id",
+			line: "#3 10.0.0.5:2001 <ping:40> <guid:5B0E4E6C-9D2F-4B7C-8C7E-1234567890AB> Medic",
+			want: models.Player{ID: "3", Name: "Medic", IP: "10.0.0.5", Port: 2001, Ping: 40, IdentityID: "5b0e4e6c-9d2f-4b7c-8c7e-1234567890ab"},
+			ok:   true,
+		},
+		{
+			name: "table verified",
+			line: "0   111.111.111.111:2304  45   0123456789abcdef0123456789abcdef(OK) PlayerName",
+			want: models.Player{ID: "0", Name: "PlayerName", IP: "111.111.111.111", Port: 2304, Ping: 45, GUID: "0123456789abcdef0123456789abcdef"},
+			ok:   true,
+		},
+		{
+			name: "table unverified in lobby",
+			line: "2   192.168.1.20:2304  -1   fedcba9876543210fedcba9876543210(?) New Guy (Lobby)",
+			want: models.Player{ID: "2", Name: "New Guy", IP: "192.168.1.20", Port: 2304, GUID: "fedcba9876543210fedcba9876543210", Lobby: true},
+			ok:   true,
+		},
+		{
+			name: "table without guid",
+			line: "#5 [2001:db8::1]:2304 60 - Sniper",
+			want: models.Player{ID: "5", Name: "Sniper", IP: "2001:db8::1", Port: 2304, Ping: 60},
+			ok:   true,
+		},
+		{
+			name: "semicolon delimited",
+			line: "1;PlayerName;5b0e4e6c-9d2f-4b7c-8c7e-1234567890ab;76561198000000000",
+			want: models.Player{ID: "1", Name: "PlayerName", IdentityID: "5b0e4e6c-9d2f-4b7c-8c7e-1234567890ab", PlatformID: "76561198000000000"},
+			ok:   true,
+		},
+		{
+			name: "pipe delimited with address",
+			line: "4 | 8.8.4.4:2001 | Pilot | 76561198000000001",
+			want: models.Player{ID: "4", Name: "Pilot", IP: "8.8.4.4", Port: 2001, PlatformID: "76561198000000001"},
+			ok:   true,
+		},
+		{
+			name: "key value",
+			line: "ID: 1 | Name: PlayerName | IdentityId: 5B0E4E6C-9D2F-4B7C-8C7E-1234567890AB | Ping: 40ms | IP: 1.2.3.4:5678",
+			want: models.Player{ID: "1", Name: "PlayerName", IdentityID: "5b0e4e6c-9d2f-4b7c-8c7e-1234567890ab", Ping: 40, IP: "1.2.3.4", Port: 5678},
+			ok:   true,
+		},
+		{
+			name: "key value with guid and steam id",
+			line: "id=7; name=Rifleman; guid=ABCDEF0123456789ABCDEF0123456789; steamid=76561198000000002",
+			want: models.Player{ID: "7", Name: "Rifleman", GUID: "abcdef0123456789abcdef0123456789", PlatformID: "76561198000000002"},
+			ok:   true,
+		},
+		{name: "plain text", line: "Server is loading", ok: false},
+		{name: "missing name", line: "1;76561198000000000", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePlayerLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (%+v)", ok, tt.ok, got)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlayerList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		ids    []string
+	}{
+		{
+			name: "battleye table",
+			output: "Players on server:\n" +
+				"[#] [IP Address]:[Port] [Ping] [GUID] [Name]\n" +
+				"--------------------------------------------------\n" +
+				"0   111.111.111.111:2304  45   0123456789abcdef0123456789abcdef(OK) PlayerName\n" +
+				"1   111.111.111.112:2304  -1   fedcba9876543210fedcba9876543210(?) Joining (Lobby)\r\n" +
+				"(2 players in total)\n",
+			ids: []string{"0", "1"},
+		},
+		{
+			name:   "tagged",
+			output: "#0 111.111.111.111:12345 <ping:123> <guid:abc123> PlayerName\n(1 players in total)",
+			ids:    []string{"0"},
+		},
+		{
+			name:   "delimited with header",
+			output: "Players:\n1;Alpha;76561198000000000\n2;Bravo;76561198000000001\n",
+			ids:    []string{"1", "2"},
+		},
+		{
+			name:   "empty server",
+			output: "Players on server:\n[#] [IP Address]:[Port] [Ping] [GUID] [Name]\n---\n(0 players in total)\n",
+			ids:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := parsePlayerList(tt.output)
+			var ids []string
+			for _, p := range players {
+				if !p.Online {
+					t.Errorf("player %s not marked online", p.ID)
+				}
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("ids = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestMaskIP(t *testing.T) {
+	tests := map[string]string{
+		"111.222.33.44": "111.222.x.x",
+		"2001:db8::1":   "2001:db8:x",
+		"not-an-ip":     "",
+	}
+	for ip, want := range tests {
+		if got := maskIP(ip); got != want {
+			t.Errorf("maskIP(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestMaskIPsInText(t *testing.T) {
+	tests := map[string]string{
+		"0   111.111.111.111:2304  45   abc(OK) Player": "0   111.111.x.x:2304  45   abc(OK) Player",
+		"Player #1 Name (10.20.30.40:2304) connected":   "Player #1 Name (10.20.x.x:2304) connected",
+		"#2 [2001:db8::1]:2304 60 - Sniper":             "#2 [2001:db8:x]:2304 60 - Sniper",
+		"Server time 12:34:56":                          "Server time 12:34:56",
+		"version 1.2.0.0":                               "version 1.2.x.x", // 形如 IPv4 的版本号同样会被隐藏
+		"no addresses here":                             "no addresses here",
+		"999.1.1.1 is invalid":                          "999.1.1.1 is invalid",
+	}
+	for in, want := range tests {
+		if got := maskIPsInText(in); got != want {
+			t.Errorf("maskIPsInText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,22 +2,15 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 	"time"
 
 	"arsm/config"
 	"arsm/models"
 	"github.com/gin-gonic/gin"
-	"github.com/multiplay/go-battleye"
 )
 
-// RCON 日志通道（用于实时推送）
-var rconLogChan = make(chan string, 100)
-
 // 从服务端 config.json 读取 RCON 配置
 func getServerRCONConfig() (string, int, string, bool) {
 	cfg := config.Get()
@@ -36,67 +29,13 @@ func getServerRCONConfig() (string, int, string, bool) {
 	return serverConfig.RCON.Address, serverConfig.RCON.Port, serverConfig.RCON.Password, true
 }
 
-// 创建 RCON 客户端
-func getRCONClient() (*battleye.Client, error) {
-	address, port, password, enabled := getServerRCONConfig()
-	if !enabled {
-		return nil, fmt.Errorf("RCON 未启用")
-	}
-	if password == "" {
-		return nil, fmt.Errorf("RCON 密码未设置")
-	}
-	// 如果地址为空，使用本机
-	if address == "" {
-		address = "127.0.0.1"
-	}
-	addr := fmt.Sprintf("%s:%d", address, port)
-	c, err := battleye.NewClient(addr, password)
-	if err != nil {
-		return nil, err
-	}
-	return c, nil
-}
-
-// 记录 RCON 命令日志
-func logRCON(command, response string) {
-	timestamp := time.Now().Format("15:04:05")
-	logLine := fmt.Sprintf("[%s] > %s\n%s", timestamp, command, response)
-	select {
-	case rconLogChan <- logLine:
-	default:
-		// 通道满，丢弃旧日志
-	}
-}
-
-// 解析 Arma Reforger RCON players 命令输出
-// Arma Reforger 格式示例：
-// #0 111.111.111.111:12345 <ping:123> <guid:abc123...> PlayerName
-// (1 players in total)
+// parsePlayers 解析 players 命令输出，在线时长取自会话记录
 func parsePlayers(output string) []models.Player {
-	var players []models.Player
-	lines := strings.Split(output, "\n")
-
-	// 匹配行格式: #ID IP:Port <ping:X> <guid:Y> Name
-	re := regexp.MustCompile(`^#(\d+)\s+([\d.:]+)\s+<ping:(\d+)>\s+<guid:([a-f0-9]+)>\s*(.+)$`)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.Contains(line, "players in total") {
-			continue
-		}
-		if strings.HasPrefix(line, "Players on server") {
-			continue
-		}
-
-		matches := re.FindStringSubmatch(line)
-		if len(matches) >= 6 {
-			player := models.Player{
-				ID:       matches[1], // Session ID
-				Name:     strings.TrimSpace(matches[5]),
-				Online:   true,
-				OnlineTime: 0, // Arma Reforger 不直接提供在线时长
-			}
-			players = append(players, player)
+	players := parsePlayerList(output)
+	now := time.Now().Unix()
+	for i := range players {
+		if since := playerSessions.onlineSince(players[i].ID); since > 0 {
+			players[i].OnlineTime = now - since
 		}
 	}
 	return players
@@ -104,76 +43,79 @@ func parsePlayers(output string) []models.Player {
 
 // GetPlayers 获取玩家列表
 func GetPlayers(c *gin.Context) {
-	client, err := getRCONClient()
-	if err != nil {
+	if _, _, err := resolveRCONAddress(); err != nil {
 		success(c, []models.Player{})
 		return
 	}
-	defer client.Close()
 
-	resp, err := client.Exec("players")
+	resp, err := rconExec("players")
 	if err != nil {
 		fail(c, "获取玩家列表失败: "+err.Error())
 		return
 	}
 
-	logRCON("players", resp)
+	playerSessions.observe(parsePlayerList(resp))
 	players := parsePlayers(resp)
+	if !isAdmin(c) {
+		for i := range players {
+			players[i].IP, players[i].Port = maskIP(players[i].IP), 0
+		}
+	}
 	success(c, players)
 }
 
-// KickPlayer 踢出玩家
+// KickPlayer 踢出玩家，可选 Body: {"reason": "...", "template": "模板名称"}
 func KickPlayer(c *gin.Context) {
-	id := c.Param("id")
-	client, err := getRCONClient()
+	var req struct {
+		Reason   string `json:"reason"`
+		Template string `json:"template"`
+	}
+	// Body 可选，缺省使用默认原因
+	c.ShouldBindJSON(&req)
+	id, err := normalizePlayerID(c.Param("id"))
 	if err != nil {
-		fail(c, "RCON 连接失败: "+err.Error())
+		fail(c, err.Error())
 		return
 	}
-	defer client.Close()
-
-	command := fmt.Sprintf("kick %s Kicked by Admin", id)
-	resp, err := client.Exec(command)
+	reason, _, err := resolveReason(req.Reason, req.Template, "kick")
 	if err != nil {
 		fail(c, "踢出失败: "+err.Error())
 		return
 	}
-
-	logRCON(command, resp)
+	command := kickCommand(id, reason)
+	if !authorizeRCON(c, command) {
+		return
+	}
+	if err := execPlayerCommand(currentUsername(c), command); err != nil {
+		fail(c, "踢出失败: "+err.Error())
+		return
+	}
 	success(c, nil)
 }
 
-// BanPlayer 封禁玩家
+// BanPlayer 封禁在线玩家，可选 Body: {"duration": 分钟, "reason": "...", "template": "模板名称"}
 func BanPlayer(c *gin.Context) {
-	id := c.Param("id")
-	client, err := getRCONClient()
+	var req banRequest
+	// Body 可选，缺省为永久封禁
+	c.ShouldBindJSON(&req)
+	id, err := normalizePlayerID(c.Param("id"))
 	if err != nil {
-		fail(c, "RCON 连接失败: "+err.Error())
+		fail(c, err.Error())
 		return
 	}
-	defer client.Close()
+	req.PlayerID, req.Identity = id, ""
 
-	// ban <id> [minutes] [reason] - 0 minutes = permanent
-	command := fmt.Sprintf("ban %s 0 Banned by Admin", id)
-	resp, err := client.Exec(command)
+	ban, applied, err := issueBan(req, currentUsername(c), rconPolicyChecker(c))
 	if err != nil {
-		fail(c, "封禁失败: "+err.Error())
+		failRCON(c, "封禁失败: ", err)
 		return
 	}
-
-	logRCON(command, resp)
-	success(c, nil)
+	success(c, gin.H{"ban": ban, "applied": applied})
 }
 
-// SendRCONCommand 发送RCON命令
+// GetRCONStatus 获取 RCON 连接状态
 func GetRCONStatus(c *gin.Context) {
-	client, err := getRCONClient()
-	if err != nil {
-		success(c, map[string]bool{"connected": false})
-		return
-	}
-	defer client.Close()
-	success(c, map[string]bool{"connected": true})
+	success(c, rconManager.Status())
 }
 
 // SendRCONCommand 发送RCON命令
@@ -185,35 +127,23 @@ func SendRCONCommand(c *gin.Context) {
 		fail(c, "无效的命令")
 		return
 	}
-
-	client, err := getRCONClient()
-	if err != nil {
-		fail(c, "RCON 连接失败: "+err.Error())
+	command := normalizeCommand(req.Command)
+	if command == "" {
+		fail(c, "命令不能为空")
+		return
+	}
+	if !authorizeRCON(c, command) {
 		return
 	}
-	defer client.Close()
 
-	resp, err := client.Exec(req.Command)
+	resp, err := rconExecAs(currentUsername(c), command)
 	if err != nil {
 		fail(c, "命令执行失败: "+err.Error())
 		return
 	}
-
-	logRCON(req.Command, resp)
-	success(c, map[string]string{"response": resp})
-}
-
-// GetRCONLogs 获取最近的 RCON 日志（WebSocket 或轮询）
-func GetRCONLogs(c *gin.Context) {
-	var logs []string
-	size := len(rconLogChan)
-	for i := 0; i < size && i < 50; i++ {
-		select {
-		case log := <-rconLogChan:
-			logs = append(logs, log)
-		default:
-			break
-		}
+	// players、bans 等命令的响应包含 IP
+	if !isAdmin(c) {
+		resp = maskIPsInText(resp)
 	}
-	success(c, logs)
+	success(c, map[string]string{"response": resp})
 }
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"arsm/models"
+	"arsm/store"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+const bucketRCONLog = "rcon_log" // 序号 -> RCONLogEntry
+
+// RCON 命令记录的保留上限：超过条数或时间的最早记录在写入新记录时删除
+const (
+	maxRCONLogEntries = 20000
+	rconLogRetention  = 90 * 24 * time.Hour
+)
+
+// rconSystemUser 定时任务、封禁同步等由 ARSM 自动下发的命令记录的用户名
+const rconSystemUser = "system"
+
+// rconLogHub 推送新的 RCON 命令记录，不影响数据库中的记录；非管理员连接到隐藏 IP 的通道
+var (
+	rconLogHub       = ws.NewHub()
+	rconLogMaskedHub = ws.NewHub()
+)
+
+// logRCON 追加一条 RCON 命令记录并推送给 WebSocket 客户端，写入失败只输出日志
+func logRCON(entry models.RCONLogEntry) {
+	if entry.Time == 0 {
+		entry.Time = time.Now().Unix()
+	}
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketRCONLog))
+		if err != nil {
+			return err
+		}
+		if entry.ID, err = b.NextSequence(); err != nil {
+			return err
+		}
+		if err := store.PutJSON(b, store.Itob(entry.ID), entry); err != nil {
+			return err
+		}
+		return pruneLogBucket(b, entry.ID, entry.Time, maxRCONLogEntries, rconLogRetention)
+	})
+	if err != nil {
+		fmt.Printf("[ARSM] 写入 RCON 日志失败: %v\n", err)
+	}
+	rconLogHub.BroadcastJSON(entry)
+	rconLogMaskedHub.BroadcastJSON(maskRCONLogEntry(entry))
+}
+
+// pruneLogBucket 删除按序号追加的记录中超出条数上限或保留时间的最早记录，在写入新记录的事务中调用。
+// 序号递增且记录不会被单独删除，因此只需从最早的一条开始检查；记录需包含 time 字段
+func pruneLogBucket(b *bolt.Bucket, lastID uint64, now int64, maxEntries uint64, retention time.Duration) error {
+	var minID uint64
+	if lastID > maxEntries {
+		minID = lastID - maxEntries
+	}
+	cutoff := now - int64(retention/time.Second)
+	cur := b.Cursor()
+	for k, v := cur.First(); k != nil; k, v = cur.First() {
+		if store.Btoi(k) > minID {
+			var e struct {
+				Time int64 `json:"time"`
+			}
+			if err := json.Unmarshal(v, &e); err == nil && e.Time >= cutoff {
+				return nil
+			}
+		}
+		if err := cur.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maskRCONLogEntry 隐藏命令和响应中的 IP（如 players、bans 的输出），供非管理员查看
+func maskRCONLogEntry(entry models.RCONLogEntry) models.RCONLogEntry {
+	entry.Command = maskIPsInText(entry.Command)
+	entry.Response = maskIPsInText(entry.Response)
+	entry.Error = maskIPsInText(entry.Error)
+	return entry
+}
+
+// rconExecAs 以指定用户身份执行命令，并记录命令、响应和耗时（失败也记录）
+func rconExecAs(user, command string) (string, error) {
+	start := time.Now()
+	resp, err := rconExec(command)
+	entry := models.RCONLogEntry{
+		Time:       start.Unix(),
+		User:       user,
+		Command:    command,
+		Response:   resp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	logRCON(entry)
+	return resp, err
+}
+
+// queryRCONLogs 从新到旧读取命令记录；before 为上一页最后一条的 ID，command 按包含匹配（不区分大小写）
+func queryRCONLogs(user, command string, before uint64, limit int) ([]models.RCONLogEntry, error) {
+	command = strings.ToLower(command)
+	entries := []models.RCONLogEntry{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketRCONLog))
+		if b == nil {
+			return nil
+		}
+		cur := b.Cursor()
+		k, v := cur.Last()
+		if before > 0 {
+			if sk, _ := cur.Seek(store.Itob(before)); sk != nil {
+				k, v = cur.Prev()
+			}
+		}
+		for ; k != nil && len(entries) < limit; k, v = cur.Prev() {
+			var e models.RCONLogEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if user != "" && e.User != user {
+				continue
+			}
+			if command != "" && !strings.Contains(strings.ToLower(e.Command), command) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// GetRCONLogs 分页获取 RCON 命令记录，从新到旧；读取不会消耗记录
+func GetRCONLogs(c *gin.Context) {
+	before, _ := strconv.ParseUint(c.Query("before"), 10, 64)
+	limit := queryInt(c, "limit", 50, 500)
+	entries, err := queryRCONLogs(c.Query("user"), c.Query("command"), before, limit)
+	if err != nil {
+		fail(c, "读取 RCON 日志失败: "+err.Error())
+		return
+	}
+	if !isAdmin(c) {
+		for i := range entries {
+			entries[i] = maskRCONLogEntry(entries[i])
+		}
+	}
+	success(c, entries)
+}
+
+// HandleRCONLogs WebSocket 实时推送 RCON 命令记录，连接后先按时间顺序发送最近 50 条
+func HandleRCONLogs(c *gin.Context) {
+	if !wsAuthorized(c) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "无效的认证令牌"})
+		return
+	}
+
+	admin := isAdmin(c)
+	history, _ := queryRCONLogs("", "", 0, 50)
+	backlog := make([]interface{}, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		if admin {
+			backlog = append(backlog, history[i])
+		} else {
+			backlog = append(backlog, maskRCONLogEntry(history[i]))
+		}
+	}
+	if admin {
+		rconLogHub.Handle(c, backlog)
+	} else {
+		rconLogMaskedHub.Handle(c, backlog)
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 宏命令中的参数占位符，如 {player}、{minutes}
+var (
+	reMacroParam     = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	reMacroParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// RCON 宏文件
+func getMacrosPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_rcon_macros.json")
+}
+
+func loadMacros() ([]models.RCONMacro, error) {
+	data, err := os.ReadFile(getMacrosPath())
+	if err != nil {
+		return []models.RCONMacro{}, nil
+	}
+	var macros []models.RCONMacro
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return []models.RCONMacro{}, nil
+	}
+	return macros, nil
+}
+
+func saveMacros(macros []models.RCONMacro) error {
+	data, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getMacrosPath(), data, 0644)
+}
+
+func findMacro(macros []models.RCONMacro, name string) int {
+	for i := range macros {
+		if macros[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// macroVisible 角色是否可见并可执行该宏，管理员可见全部
+func macroVisible(m models.RCONMacro, role string) bool {
+	if len(m.Roles) == 0 || role == "admin" {
+		return true
+	}
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMacro 检查宏定义，并为命令中引用但未声明的参数补充声明
+func validateMacro(m *models.RCONMacro) error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	steps := make([]string, 0, len(m.Steps))
+	for _, s := range m.Steps {
+		if s = strings.TrimSpace(s); s != "" {
+			if strings.ContainsAny(s, "\r\n") {
+				return fmt.Errorf("每条命令只能占一行: %s", s)
+			}
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("至少需要一条命令")
+	}
+	m.Steps = steps
+
+	declared := make(map[string]bool)
+	for i := range m.Params {
+		p := &m.Params[i]
+		p.Name = strings.TrimSpace(p.Name)
+		if !reMacroParamName.MatchString(p.Name) {
+			return fmt.Errorf("无效的参数名: %s", p.Name)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf("参数重复: %s", p.Name)
+		}
+		declared[p.Name] = true
+		switch p.Type {
+		case "":
+			p.Type = "string"
+		case "string", "int", "player":
+		default:
+			return fmt.Errorf("参数 %s 的类型只能是 string、int 或 player", p.Name)
+		}
+	}
+	for _, s := range m.Steps {
+		for _, match := range reMacroParam.FindAllStringSubmatch(s, -1) {
+			if !declared[match[1]] {
+				declared[match[1]] = true
+				m.Params = append(m.Params, models.MacroParam{Name: match[1], Type: "string", Required: true})
+			}
+		}
+	}
+	return nil
+}
+
+// resolveMacroParams 校验参数值并展开命令
+func resolveMacroParams(m models.RCONMacro, values map[string]string) ([]string, error) {
+	resolved := make(map[string]string, len(m.Params))
+	for _, p := range m.Params {
+		v := strings.TrimSpace(values[p.Name])
+		if v == "" {
+			v = p.Default
+		}
+		if v == "" {
+			if p.Required {
+				return nil, fmt.Errorf("缺少参数: %s", p.Name)
+			}
+			resolved[p.Name] = ""
+			continue
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("参数 %s 不能包含换行", p.Name)
+		}
+		switch p.Type {
+		case "int":
+			if _, err := strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("参数 %s 必须是整数", p.Name)
+			}
+		case "player":
+			v = strings.TrimPrefix(v, "#")
+			if !isDigits(v) {
+				return nil, fmt.Errorf("参数 %s 必须是玩家会话编号", p.Name)
+			}
+		}
+		resolved[p.Name] = v
+	}
+
+	commands := make([]string, len(m.Steps))
+	for i, s := range m.Steps {
+		commands[i] = normalizeCommand(reMacroParam.ReplaceAllStringFunc(s, func(match string) string {
+			return resolved[match[1:len(match)-1]]
+		}))
+	}
+	return commands, nil
+}
+
+// runMacroSteps 依次执行命令，返回每一步的结果
+func runMacroSteps(user string, commands []string, stopOnError bool) ([]models.MacroStepResult, bool) {
+	results := make([]models.MacroStepResult, 0, len(commands))
+	ok := true
+	for _, command := range commands {
+		if !ok && stopOnError {
+			results = append(results, models.MacroStepResult{Command: command, Skipped: true})
+			continue
+		}
+		start := time.Now()
+		resp, err := rconExecAs(user, command)
+		step := models.MacroStepResult{
+			Command:    command,
+			Response:   resp,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			step.Error = err.Error()
+			ok = false
+		}
+		results = append(results, step)
+	}
+	return results, ok
+}
+
+// formatMacroParams 审计记录中的参数摘要
+func formatMacroParams(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+values[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// GetMacros 获取当前角色可见的宏
+func GetMacros(c *gin.Context) {
+	macros, _ := loadMacros()
+	role := currentRole(c)
+	result := []models.RCONMacro{}
+	for _, m := range macros {
+		if macroVisible(m, role) {
+			result = append(result, m)
+		}
+	}
+	success(c, result)
+}
+
+// CreateMacro 新建宏（仅管理员）
+func CreateMacro(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var m models.RCONMacro
+	if err := c.ShouldBindJSON(&m); err != nil {
+		fail(c, "无效的宏数据")
+		return
+	}
+	if err := validateMacro(&m); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	macros, _ := loadMacros()
+	if findMacro(macros, m.Name) >= 0 {
+		fail(c, "宏名称已存在")
+		return
+	}
+	macros = append(macros, m)
+	if err := saveMacros(macros); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, m)
+}
+
+// UpdateMacro 修改宏（仅管理员，可改名）
+func UpdateMacro(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var m models.RCONMacro
+	if err := c.ShouldBindJSON(&m); err != nil {
+		fail(c, "无效的宏数据")
+		return
+	}
+	name := c.Param("name")
+	if m.Name == "" {
+		m.Name = name
+	}
+	if err := validateMacro(&m); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	macros, _ := loadMacros()
+	i := findMacro(macros, name)
+	if i < 0 {
+		fail(c, "宏不存在")
+		return
+	}
+	if m.Name != name && findMacro(macros, m.Name) >= 0 {
+		fail(c, "宏名称已存在")
+		return
+	}
+	macros[i] = m
+	if err := saveMacros(macros); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, m)
+}
+
+// DeleteMacro 删除宏（仅管理员）
+func DeleteMacro(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	macros, _ := loadMacros()
+	i := findMacro(macros, c.Param("name"))
+	if i < 0 {
+		fail(c, "宏不存在")
+		return
+	}
+	macros = append(macros[:i], macros[i+1:]...)
+	if err := saveMacros(macros); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// RunMacro 执行宏，返回每一步的响应，并写入审计记录
+func RunMacro(c *gin.Context) {
+	var req struct {
+		Params map[string]string `json:"params"`
+	}
+	// Body 可选，无参数的宏可以不传
+	c.ShouldBindJSON(&req)
+
+	macros, _ := loadMacros()
+	i := findMacro(macros, c.Param("name"))
+	if i < 0 || !macroVisible(macros[i], currentRole(c)) {
+		fail(c, "宏不存在")
+		return
+	}
+	m := macros[i]
+
+	commands, err := resolveMacroParams(m, req.Params)
+	if err != nil {
+		fail(c, err.Error())
+		return
+	}
+	// 宏中的每条命令同样受角色命令策略限制
+	if !authorizeRCON(c, commands...) {
+		return
+	}
+	steps, ok := runMacroSteps(currentUsername(c), commands, m.StopOnError)
+	if !isAdmin(c) {
+		for i := range steps {
+			steps[i].Response = maskIPsInText(steps[i].Response)
+		}
+	}
+
+	failed := 0
+	for _, s := range steps {
+		if s.Error != "" || s.Skipped {
+			failed++
+		}
+	}
+	recordAudit(models.AuditEntry{
+		User:    currentUsername(c),
+		Action:  "macro.run",
+		Target:  m.Name,
+		Detail:  strings.TrimSpace(fmt.Sprintf("%s (%d/%d 成功)", formatMacroParams(req.Params), len(steps)-failed, len(steps))),
+		Success: ok,
+	})
+
+	data := gin.H{"macro": m.Name, "success": ok, "steps": steps}
+	if !ok {
+		failWithData(c, "部分命令执行失败", data)
+		return
+	}
+	success(c, data)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/auth"
+	"arsm/models"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+	"github.com/multiplay/go-battleye"
+)
+
+// RCON 消息类型
+const (
+	rconMsgConnect    = "connect"
+	rconMsgDisconnect = "disconnect"
+	rconMsgGUID       = "guid"
+	rconMsgChat       = "chat"
+	rconMsgKick       = "kick"
+	rconMsgAdmin      = "admin"
+	rconMsgOther      = "other"
+)
+
+// 保留的服务端消息条数
+const maxRCONMessages = 1000
+
+var (
+	rconMessages   []models.RCONMessage
+	rconMessagesMu sync.Mutex
+	rconMessageSeq int64
+
+	// 推送服务端消息的 WebSocket 通道，与控制台日志分开；非管理员连接到隐藏 IP 的通道
+	rconMessageHub       = ws.NewHub()
+	rconMessageMaskedHub = ws.NewHub()
+
+	// 收到服务端消息后的回调
+	rconMessageListeners   []func(models.RCONMessage)
+	rconMessageListenersMu sync.Mutex
+)
+
+// BattlEye 服务端消息格式
+var (
+	reRCONConnect    = regexp.MustCompile(`^Player #(\d+) (.+?) \(([\d.]+):(\d+)\) connected$`)
+	reRCONDisconnect = regexp.MustCompile(`^Player #(\d+) (.+?) disconnected$`)
+	reRCONGUID       = regexp.MustCompile(`^Player #(\d+) (.+?) - (?:BE )?GUID: ([0-9A-Za-z-]+)$`)
+	reRCONVerified   = regexp.MustCompile(`^Verified GUID \(([0-9A-Za-z-]+)\) of player #(\d+) (.+)$`)
+	reRCONKick       = regexp.MustCompile(`^Player #(\d+) (.+?)(?: \(([0-9A-Za-z-]+)\))? has been kicked by (?:BattlEye|.+?): (.*)$`)
+	reRCONChat       = regexp.MustCompile(`^\(([A-Za-z]+)\) (.+?): (.*)$`)
+	reRCONAdmin      = regexp.MustCompile(`^RCon admin #(\d+)(?: \((.+?)\))? logged in$`)
+)
+
+func init() {
+	rconManager.OnConnect(consumeRCONMessages)
+}
+
+// classifyRCONMessage 识别服务端消息类型并提取玩家信息
+func classifyRCONMessage(raw string) models.RCONMessage {
+	line := strings.TrimSpace(raw)
+	msg := models.RCONMessage{Time: time.Now().Unix(), Type: rconMsgOther, Raw: line}
+
+	if m := reRCONConnect.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgConnect
+		msg.PlayerID, msg.PlayerName, msg.IP = m[1], m[2], m[3]
+	} else if m := reRCONDisconnect.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgDisconnect
+		msg.PlayerID, msg.PlayerName = m[1], m[2]
+	} else if m := reRCONGUID.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgGUID
+		msg.PlayerID, msg.PlayerName, msg.GUID = m[1], m[2], m[3]
+	} else if m := reRCONVerified.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgGUID
+		msg.GUID, msg.PlayerID, msg.PlayerName = m[1], m[2], m[3]
+	} else if m := reRCONKick.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgKick
+		msg.PlayerID, msg.PlayerName, msg.GUID, msg.Text = m[1], m[2], m[3], m[4]
+	} else if m := reRCONAdmin.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgAdmin
+		msg.PlayerID, msg.IP = m[1], m[2]
+		if i := strings.LastIndex(msg.IP, ":"); i > 0 {
+			msg.IP = msg.IP[:i]
+		}
+	} else if m := reRCONChat.FindStringSubmatch(line); m != nil {
+		msg.Type = rconMsgChat
+		msg.Channel, msg.PlayerName, msg.Text = m[1], m[2], m[3]
+	}
+	return msg
+}
+
+// recordRCONMessage 保存到历史、推送到 WebSocket 并通知监听者
+func recordRCONMessage(msg models.RCONMessage) {
+	rconMessagesMu.Lock()
+	rconMessageSeq++
+	msg.ID = rconMessageSeq
+	rconMessages = append(rconMessages, msg)
+	if len(rconMessages) > maxRCONMessages {
+		rconMessages = rconMessages[len(rconMessages)-maxRCONMessages:]
+	}
+	rconMessagesMu.Unlock()
+
+	rconMessageHub.BroadcastJSON(msg)
+	rconMessageMaskedHub.BroadcastJSON(maskRCONMessage(msg))
+
+	rconMessageListenersMu.Lock()
+	listeners := append([]func(models.RCONMessage){}, rconMessageListeners...)
+	rconMessageListenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(msg)
+	}
+}
+
+// maskRCONMessage 隐藏消息中的 IP，供非管理员查看
+func maskRCONMessage(msg models.RCONMessage) models.RCONMessage {
+	if msg.IP != "" {
+		msg.IP = maskIP(msg.IP)
+	}
+	msg.Raw = maskIPsInText(msg.Raw)
+	msg.Text = maskIPsInText(msg.Text)
+	return msg
+}
+
+// onRCONMessage 注册服务端消息监听者
+func onRCONMessage(listener func(models.RCONMessage)) {
+	rconMessageListenersMu.Lock()
+	defer rconMessageListenersMu.Unlock()
+	rconMessageListeners = append(rconMessageListeners, listener)
+}
+
+// consumeRCONMessages 读取连接上的服务端消息，连接关闭时退出
+func consumeRCONMessages(client *battleye.Client) {
+	go func() {
+		for raw := range client.Messages() {
+			recordRCONMessage(classifyRCONMessage(raw))
+		}
+	}()
+}
+
+// queryRCONMessages 按类型和起始 ID 查询历史消息
+func queryRCONMessages(types map[string]bool, afterID int64, limit int) []models.RCONMessage {
+	rconMessagesMu.Lock()
+	defer rconMessagesMu.Unlock()
+
+	result := []models.RCONMessage{}
+	// 从新到旧取最近的 limit 条
+	for i := len(rconMessages) - 1; i >= 0 && len(result) < limit; i-- {
+		m := rconMessages[i]
+		if m.ID <= afterID {
+			break
+		}
+		if len(types) > 0 && !types[m.Type] {
+			continue
+		}
+		result = append(result, m)
+	}
+	// 按时间正序返回
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// parseTypeFilter 解析 type 查询参数（可重复或逗号分隔）
+func parseTypeFilter(c *gin.Context) map[string]bool {
+	types := make(map[string]bool)
+	for _, v := range c.QueryArray("type") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types[t] = true
+			}
+		}
+	}
+	return types
+}
+
+// GetRCONMessages 获取服务端推送消息的历史
+func GetRCONMessages(c *gin.Context) {
+	afterID, _ := strconv.ParseInt(c.Query("after"), 10, 64)
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 || limit > maxRCONMessages {
+		limit = 200
+	}
+	messages := queryRCONMessages(parseTypeFilter(c), afterID, limit)
+	if !isAdmin(c) {
+		for i := range messages {
+			messages[i] = maskRCONMessage(messages[i])
+		}
+	}
+	success(c, messages)
+}
+
+// wsAuthorized WebSocket 无法设置 Authorization 头，认证启用时通过 ?token= 传递令牌
+func wsAuthorized(c *gin.Context) bool {
+	if !auth.GetUserManager().IsEnabled() {
+		return true
+	}
+	claims, err := auth.ParseToken(c.Query("token"))
+	if err != nil {
+		return false
+	}
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	return true
+}
+
+// HandleRCONMessages 通过 WebSocket 推送服务端消息，连接后先发送最近的历史
+func HandleRCONMessages(c *gin.Context) {
+	if !wsAuthorized(c) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "无效的认证令牌"})
+		return
+	}
+
+	admin := isAdmin(c)
+	history := queryRCONMessages(nil, 0, 100)
+	backlog := make([]interface{}, 0, len(history))
+	for _, m := range history {
+		if !admin {
+			m = maskRCONMessage(m)
+		}
+		backlog = append(backlog, m)
+	}
+	if admin {
+		rconMessageHub.Handle(c, backlog)
+	} else {
+		rconMessageMaskedHub.Handle(c, backlog)
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"arsm/config"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRCONPolicies 未配置时的默认策略：管理员不受限，普通用户只能查看封禁、私信和踢出。
+// 玩家列表通过 /api/rcon/players 获取（IP 已隐藏），不开放 players 命令
+var defaultRCONPolicies = []models.RCONPolicy{
+	{Role: "admin", Allow: []string{"*"}},
+	{Role: "user", Allow: []string{"bans", "say", "kick"}},
+}
+
+// RCON 权限策略文件
+func getRCONPolicyPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_rcon_policy.json")
+}
+
+func loadRCONPolicies() []models.RCONPolicy {
+	data, err := os.ReadFile(getRCONPolicyPath())
+	if err != nil {
+		return defaultRCONPolicies
+	}
+	var policies []models.RCONPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return defaultRCONPolicies
+	}
+	return policies
+}
+
+func saveRCONPolicies(policies []models.RCONPolicy) error {
+	data, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getRCONPolicyPath(), data, 0644)
+}
+
+// compileCommandPattern 将命令模式转换为正则（不区分大小写）：
+// 不含空格和通配符的模式只匹配命令名（参数任意），否则匹配整条命令，* 匹配任意字符，? 匹配单个字符
+func compileCommandPattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	if pattern == "" {
+		return nil, fmt.Errorf("命令模式不能为空")
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	expr = strings.ReplaceAll(expr, `\?`, `.`)
+	if !strings.ContainsAny(pattern, " *?") {
+		expr += `(?: .*)?`
+	}
+	return regexp.Compile(`(?i)^` + expr + `$`)
+}
+
+// matchCommandPatterns 命令是否命中任一模式
+func matchCommandPatterns(patterns []string, command string) bool {
+	for _, p := range patterns {
+		re, err := compileCommandPattern(p)
+		if err == nil && re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCommand 合并命令中的连续空白，检查权限和下发命令都应使用规范化后的同一字符串
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// commandAllowed 角色是否可以执行该命令（按原样匹配，调用方需先规范化）；没有策略的角色不能执行任何命令
+func commandAllowed(role, command string) bool {
+	for _, p := range loadRCONPolicies() {
+		if p.Role != role {
+			continue
+		}
+		if matchCommandPatterns(p.Deny, command) {
+			return false
+		}
+		return matchCommandPatterns(p.Allow, command)
+	}
+	return false
+}
+
+// rconDeniedError 命令被权限策略拒绝
+type rconDeniedError struct {
+	role    string
+	command string
+}
+
+func (e *rconDeniedError) Error() string {
+	return fmt.Sprintf("角色 %s 无权执行命令: %s", e.role, e.command)
+}
+
+// checkRCONPolicy 检查当前用户能否执行命令，被拒绝时写入审计记录并返回 *rconDeniedError
+func checkRCONPolicy(c *gin.Context, command string) error {
+	role := currentRole(c)
+	if commandAllowed(role, command) {
+		return nil
+	}
+	recordAudit(models.AuditEntry{
+		User:   currentUsername(c),
+		Action: "rcon.denied",
+		Target: command,
+		Detail: "角色: " + role,
+	})
+	return &rconDeniedError{role: role, command: command}
+}
+
+// rconPolicyChecker 供 issueBan 等内部函数在下发命令前检查当前用户的权限
+func rconPolicyChecker(c *gin.Context) func(string) error {
+	return func(command string) error {
+		return checkRCONPolicy(c, command)
+	}
+}
+
+// failRCON 命令被拒绝时返回 403，其他错误按普通失败处理
+func failRCON(c *gin.Context, message string, err error) {
+	var denied *rconDeniedError
+	if errors.As(err, &denied) {
+		c.JSON(http.StatusForbidden, Response{Code: 403, Message: err.Error()})
+		return
+	}
+	fail(c, message+err.Error())
+}
+
+// authorizeRCON 检查所有命令，任一被拒绝时返回 403，调用方应直接返回
+func authorizeRCON(c *gin.Context, commands ...string) bool {
+	for _, command := range commands {
+		if err := checkRCONPolicy(c, command); err != nil {
+			failRCON(c, "", err)
+			return false
+		}
+	}
+	return true
+}
+
+// GetRCONPolicies 获取 RCON 权限策略（仅管理员）
+func GetRCONPolicies(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	success(c, loadRCONPolicies())
+}
+
+// SaveRCONPolicies 保存 RCON 权限策略（仅管理员，整体替换）
+func SaveRCONPolicies(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var policies []models.RCONPolicy
+	if err := c.ShouldBindJSON(&policies); err != nil {
+		fail(c, "无效的策略数据")
+		return
+	}
+	seen := make(map[string]bool)
+	for i := range policies {
+		p := &policies[i]
+		p.Role = strings.TrimSpace(p.Role)
+		if p.Role == "" {
+			fail(c, "角色不能为空")
+			return
+		}
+		if seen[p.Role] {
+			fail(c, "角色重复: "+p.Role)
+			return
+		}
+		seen[p.Role] = true
+		for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+			if _, err := compileCommandPattern(pattern); err != nil {
+				fail(c, fmt.Sprintf("角色 %s 的命令模式无效: %v", p.Role, err))
+				return
+			}
+		}
+	}
+	if err := saveRCONPolicies(policies); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, policies)
+}
+
+// CheckRCONPolicy 测试某角色能否执行命令（仅管理员）
+func CheckRCONPolicy(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	role := c.DefaultQuery("role", "user")
+	command := normalizeCommand(c.Query("command"))
+	success(c, gin.H{"role": role, "command": command, "allowed": commandAllowed(role, command)})
+}
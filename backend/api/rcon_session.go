@@ -0,0 +1,260 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-battleye"
+)
+
+// RCON 连接状态
+const (
+	rconStateDisabled     = "disabled" // config.json 中未启用 RCON
+	rconStateDisconnected = "disconnected"
+	rconStateConnecting   = "connecting"
+	rconStateConnected    = "connected"
+)
+
+const (
+	rconDialTimeout   = 5 * time.Second
+	rconCheckInterval = 5 * time.Second
+	rconProbeInterval = 15 * time.Second
+	rconMinBackoff    = 1 * time.Second
+	rconMaxBackoff    = 60 * time.Second
+)
+
+// RCONStatus RCON 连接状态
+type RCONStatus struct {
+	Connected   bool   `json:"connected"`
+	State       string `json:"state"`
+	Address     string `json:"address,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+	LastErrorAt int64  `json:"last_error_at,omitempty"`
+	LatencyMs   int64  `json:"latency_ms"`
+	ConnectedAt int64  `json:"connected_at,omitempty"`
+	Attempts    int    `json:"reconnect_attempts"`
+	NextRetryAt int64  `json:"next_retry_at,omitempty"`
+}
+
+// rconSession 长连接的 RCON 会话，所有 RCON 请求复用同一个登录后的客户端
+type rconSession struct {
+	mu          sync.Mutex
+	client      *battleye.Client
+	addr        string
+	password    string
+	state       string
+	lastError   string
+	lastErrorAt time.Time
+	latency     time.Duration
+	connectedAt time.Time
+	attempts    int
+	nextRetry   time.Time
+
+	// 新连接建立后的回调（在锁外调用）
+	onConnect []func(*battleye.Client)
+}
+
+var rconManager = &rconSession{state: rconStateDisconnected}
+
+// resolveRCONAddress 读取 config.json 中的 RCON 地址和密码
+func resolveRCONAddress() (string, string, error) {
+	address, port, password, enabled := getServerRCONConfig()
+	if !enabled {
+		return "", "", errRCONDisabled
+	}
+	if password == "" {
+		return "", "", errors.New("RCON 密码未设置")
+	}
+	// 如果地址为空，使用本机
+	if address == "" || address == "0.0.0.0" {
+		address = "127.0.0.1"
+	}
+	return fmt.Sprintf("%s:%d", address, port), password, nil
+}
+
+var errRCONDisabled = errors.New("RCON 未启用")
+
+// closeLocked 关闭当前客户端（需持有锁）
+func (s *rconSession) closeLocked() {
+	if s.client != nil {
+		client := s.client
+		s.client = nil
+		// Close 会等待内部 goroutine 退出，放到后台避免阻塞请求
+		go client.Close()
+	}
+	s.connectedAt = time.Time{}
+}
+
+// failLocked 记录错误并安排下一次重连（指数退避）
+func (s *rconSession) failLocked(err error) {
+	s.closeLocked()
+	s.state = rconStateDisconnected
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+	s.attempts++
+	backoff := rconMinBackoff << uint(s.attempts-1)
+	if backoff > rconMaxBackoff || backoff <= 0 {
+		backoff = rconMaxBackoff
+	}
+	s.nextRetry = time.Now().Add(backoff)
+}
+
+// connect 获取可用的客户端，必要时建立新连接
+// force 为 false 时遵守退避时间，避免频繁登录
+func (s *rconSession) connect(force bool) (*battleye.Client, error) {
+	addr, password, err := resolveRCONAddress()
+
+	s.mu.Lock()
+	if err != nil {
+		s.closeLocked()
+		if errors.Is(err, errRCONDisabled) {
+			s.state = rconStateDisabled
+			s.lastError = ""
+		} else {
+			s.state = rconStateDisconnected
+			s.lastError = err.Error()
+			s.lastErrorAt = time.Now()
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// 配置变化时重新连接
+	if s.client != nil && (s.addr != addr || s.password != password) {
+		s.closeLocked()
+		s.state = rconStateDisconnected
+	}
+	if s.client != nil {
+		client := s.client
+		s.mu.Unlock()
+		return client, nil
+	}
+	if !force && time.Now().Before(s.nextRetry) {
+		lastError := s.lastError
+		s.mu.Unlock()
+		return nil, fmt.Errorf("RCON 未连接（等待重连）: %s", lastError)
+	}
+	s.state = rconStateConnecting
+	s.mu.Unlock()
+
+	start := time.Now()
+	client, err := battleye.NewClient(addr, password, battleye.Timeout(rconDialTimeout))
+
+	s.mu.Lock()
+	if err != nil {
+		s.failLocked(err)
+		s.mu.Unlock()
+		return nil, err
+	}
+	if s.client != nil {
+		// 并发建立了连接，保留先建立的那个
+		existing := s.client
+		s.mu.Unlock()
+		client.Close()
+		return existing, nil
+	}
+	s.client = client
+	s.addr = addr
+	s.password = password
+	s.state = rconStateConnected
+	s.connectedAt = time.Now()
+	s.latency = time.Since(start)
+	s.attempts = 0
+	s.nextRetry = time.Time{}
+	callbacks := append([]func(*battleye.Client){}, s.onConnect...)
+	s.mu.Unlock()
+
+	for _, cb := range callbacks {
+		cb(client)
+	}
+	return client, nil
+}
+
+// Exec 通过共享连接执行命令，连接失效时标记断开以便重连
+func (s *rconSession) Exec(command string) (string, error) {
+	client, err := s.connect(false)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	resp, err := client.Exec(command)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if s.client == client {
+			s.failLocked(err)
+		}
+		return "", err
+	}
+	s.latency = time.Since(start)
+	return resp, nil
+}
+
+// Connected 当前是否已连接
+func (s *rconSession) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client != nil
+}
+
+// Status 获取连接状态
+func (s *rconSession) Status() RCONStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := RCONStatus{
+		Connected: s.client != nil,
+		State:     s.state,
+		Address:   s.addr,
+		LastError: s.lastError,
+		LatencyMs: s.latency.Milliseconds(),
+		Attempts:  s.attempts,
+	}
+	if !s.lastErrorAt.IsZero() {
+		status.LastErrorAt = s.lastErrorAt.Unix()
+	}
+	if !s.connectedAt.IsZero() {
+		status.ConnectedAt = s.connectedAt.Unix()
+	}
+	if s.client == nil && !s.nextRetry.IsZero() {
+		status.NextRetryAt = s.nextRetry.Unix()
+	}
+	return status
+}
+
+// OnConnect 注册新连接建立后的回调
+func (s *rconSession) OnConnect(cb func(*battleye.Client)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onConnect = append(s.onConnect, cb)
+}
+
+// probe 保活并测量延迟；未连接时按退避时间尝试重连
+func (s *rconSession) probe(lastProbe *time.Time) {
+	if !s.Connected() {
+		s.connect(false)
+		return
+	}
+	if time.Since(*lastProbe) >= rconProbeInterval {
+		*lastProbe = time.Now()
+		s.Exec("")
+	}
+}
+
+// rconExec 执行 RCON 命令（复用共享连接）
+func rconExec(command string) (string, error) {
+	return rconManager.Exec(command)
+}
+
+// StartRCONManager 启动 RCON 连接保活和自动重连
+func StartRCONManager() {
+	go func() {
+		var lastProbe time.Time
+		for {
+			rconManager.probe(&lastProbe)
+			time.Sleep(rconCheckInterval)
+		}
+	}()
+}
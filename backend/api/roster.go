@@ -0,0 +1,465 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/models"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+)
+
+const rosterCheckInterval = 5 * time.Minute
+
+// rosterCSVHeader 导入导出的 CSV 列
+var rosterCSVHeader = []string{"identity", "name", "note", "admin", "expires_at"}
+
+// rosterMu 名单文件的读-改-写需持有该锁，避免并发修改时丢失改动；写入配置时先持有 rosterMu 再持有 configMu
+var rosterMu sync.Mutex
+
+// 受信任玩家名单文件
+func getRosterPath() string {
+	cfg := config.Get()
+	return filepath.Join(cfg.ServerPath, "arsm_roster.json")
+}
+
+func loadRoster() []models.RosterEntry {
+	data, err := os.ReadFile(getRosterPath())
+	if err != nil {
+		return []models.RosterEntry{}
+	}
+	var roster []models.RosterEntry
+	if err := json.Unmarshal(data, &roster); err != nil {
+		return []models.RosterEntry{}
+	}
+	return roster
+}
+
+func saveRoster(roster []models.RosterEntry) error {
+	// 运行状态不写入文件
+	stored := make([]models.RosterEntry, len(roster))
+	for i, e := range roster {
+		e.Expired = false
+		stored[i] = e
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Name < stored[j].Name })
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getRosterPath(), data, 0644)
+}
+
+func findRosterEntry(roster []models.RosterEntry, identity string) int {
+	identity = strings.ToLower(identity)
+	for i := range roster {
+		if roster[i].Identity == identity {
+			return i
+		}
+	}
+	return -1
+}
+
+// rosterExpired 名单条目是否已过期
+func rosterExpired(e models.RosterEntry, now int64) bool {
+	return e.ExpiresAt > 0 && e.ExpiresAt <= now
+}
+
+// validateRosterEntry 检查标识格式（Bohemia 身份 ID 或 Steam64）并规范化字段
+func validateRosterEntry(e *models.RosterEntry) error {
+	e.Identity = strings.TrimSpace(e.Identity)
+	switch {
+	case reIdentityID.MatchString(e.Identity):
+		e.Identity = strings.ToLower(e.Identity)
+	case reSteamID.MatchString(e.Identity):
+	default:
+		return fmt.Errorf("无效的玩家标识: %s（应为 Bohemia 身份 ID 或 Steam64 ID）", e.Identity)
+	}
+	e.Name = strings.TrimSpace(e.Name)
+	e.Note = strings.TrimSpace(e.Note)
+	if e.ExpiresAt < 0 {
+		return fmt.Errorf("过期时间无效")
+	}
+	return nil
+}
+
+// rosterTrusted 在线玩家是否在有效名单中（自动管理规则跳过这些玩家）
+func rosterTrusted(roster []models.RosterEntry, p models.Player, now int64) bool {
+	for _, e := range roster {
+		if rosterExpired(e, now) {
+			continue
+		}
+		if e.Identity == p.IdentityID || e.Identity == p.PlatformID {
+			return true
+		}
+	}
+	return false
+}
+
+// renderRosterList 计算名单管理的配置列表：保留手动添加的条目，名单中的标识以名单为准，
+// include 为 true 且未过期的条目写入列表；removed 为刚从名单删除的标识
+func renderRosterList(list []string, roster []models.RosterEntry, now int64, include func(models.RosterEntry) bool, removed ...string) []string {
+	managed := make(map[string]bool)
+	for _, e := range roster {
+		managed[e.Identity] = true
+	}
+	for _, id := range removed {
+		managed[strings.ToLower(id)] = true
+	}
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, a := range list {
+		key := strings.ToLower(strings.TrimSpace(a))
+		if key == "" || managed[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, a)
+	}
+	for _, e := range roster {
+		if include(e) && !rosterExpired(e, now) && !seen[e.Identity] {
+			seen[e.Identity] = true
+			result = append(result, e.Identity)
+		}
+	}
+	return result
+}
+
+// renderRosterAdmins 计算 game.admins：名单中标记为管理员的条目
+func renderRosterAdmins(admins []string, roster []models.RosterEntry, now int64, removed ...string) []string {
+	return renderRosterList(admins, roster, now, func(e models.RosterEntry) bool { return e.Admin }, removed...)
+}
+
+// renderRosterWhitelist 计算 rcon.whitelist：名单中的全部条目
+func renderRosterWhitelist(whitelist []string, roster []models.RosterEntry, now int64, removed ...string) []string {
+	return renderRosterList(whitelist, roster, now, func(models.RosterEntry) bool { return true }, removed...)
+}
+
+// renderRosterInto 按名单改写某个配置文件的 game.admins 和 rcon.whitelist（没有 rcon 段时跳过），
+// 内容不变时不写文件；文件不存在时跳过
+func renderRosterInto(path string, roster []models.RosterEntry, now int64, removed ...string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil
+	}
+	var serverConfig models.ServerConfig
+	if err := json.Unmarshal(data, &serverConfig); err != nil {
+		return false, err
+	}
+	changed := false
+	admins := renderRosterAdmins(serverConfig.Game.Admins, roster, now, removed...)
+	if strings.Join(admins, "\n") != strings.Join(serverConfig.Game.Admins, "\n") {
+		serverConfig.Game.Admins = admins
+		changed = true
+	}
+	if serverConfig.RCON != nil {
+		whitelist := renderRosterWhitelist(serverConfig.RCON.Whitelist, roster, now, removed...)
+		if strings.Join(whitelist, "\n") != strings.Join(serverConfig.RCON.Whitelist, "\n") {
+			serverConfig.RCON.Whitelist = whitelist
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	newData, _ := json.MarshalIndent(serverConfig, "", "  ")
+	if err := writeFileAtomic(path, newData, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyRoster 将名单写入 config.json 和使用共享管理员列表的预设（game.admins、rcon.whitelist）
+func applyRoster(removed ...string) (bool, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	roster := loadRoster()
+	now := time.Now().Unix()
+	changed, err := renderRosterInto(getConfigPath(), roster, now, removed...)
+	if err != nil {
+		return changed, err
+	}
+	var failed []string
+	for _, name := range loadSharedAdminPresets() {
+		c, err := renderRosterInto(getPresetPath(name), roster, now, removed...)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("预设 %s: %v", name, err))
+		}
+		changed = changed || c
+	}
+	if len(failed) > 0 {
+		return changed, fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return changed, nil
+}
+
+// StartRosterExpiry 定期从 config.json 中移除已过期的名单条目
+func StartRosterExpiry() {
+	go func() {
+		for {
+			time.Sleep(rosterCheckInterval)
+			if changed, err := applyRoster(); err != nil {
+				fmt.Printf("[ARSM] 更新名单失败: %v\n", err)
+			} else if changed {
+				ws.Broadcast("[名单] 已过期的条目已从 config.json 移除，重启服务端后生效")
+			}
+		}
+	}()
+}
+
+// withRosterState 填充过期状态
+func withRosterState(roster []models.RosterEntry, now int64) []models.RosterEntry {
+	for i := range roster {
+		roster[i].Expired = rosterExpired(roster[i], now)
+	}
+	return roster
+}
+
+// saveAndApplyRoster 保存名单并写入 config.json，返回写入失败的提示
+func saveAndApplyRoster(roster []models.RosterEntry, removed ...string) error {
+	if err := saveRoster(roster); err != nil {
+		return err
+	}
+	if _, err := applyRoster(removed...); err != nil {
+		return fmt.Errorf("名单已保存，但写入配置失败: %v", err)
+	}
+	return nil
+}
+
+// GetRoster 获取受信任玩家名单
+func GetRoster(c *gin.Context) {
+	success(c, withRosterState(loadRoster(), time.Now().Unix()))
+}
+
+// CreateRosterEntry 添加名单条目（仅管理员）
+func CreateRosterEntry(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var e models.RosterEntry
+	if err := c.ShouldBindJSON(&e); err != nil {
+		fail(c, "无效的名单数据")
+		return
+	}
+	if err := validateRosterEntry(&e); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	if findRosterEntry(roster, e.Identity) >= 0 {
+		fail(c, "该玩家已在名单中")
+		return
+	}
+	e.AddedBy, e.AddedAt = currentUsername(c), time.Now().Unix()
+	roster = append(roster, e)
+	if err := saveAndApplyRoster(roster); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, withRosterState([]models.RosterEntry{e}, time.Now().Unix())[0])
+}
+
+// UpdateRosterEntry 修改名单条目（仅管理员，标识不可修改）
+func UpdateRosterEntry(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var e models.RosterEntry
+	if err := c.ShouldBindJSON(&e); err != nil {
+		fail(c, "无效的名单数据")
+		return
+	}
+	e.Identity = c.Param("identity")
+	if err := validateRosterEntry(&e); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	i := findRosterEntry(roster, e.Identity)
+	if i < 0 {
+		fail(c, "名单中没有该玩家")
+		return
+	}
+	e.AddedBy, e.AddedAt = roster[i].AddedBy, roster[i].AddedAt
+	roster[i] = e
+	if err := saveAndApplyRoster(roster); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, withRosterState([]models.RosterEntry{e}, time.Now().Unix())[0])
+}
+
+// DeleteRosterEntry 从名单删除（仅管理员），同时从 game.admins 移除
+func DeleteRosterEntry(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	identity := c.Param("identity")
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	i := findRosterEntry(roster, identity)
+	if i < 0 {
+		fail(c, "名单中没有该玩家")
+		return
+	}
+	roster = append(roster[:i], roster[i+1:]...)
+	if err := saveAndApplyRoster(roster, identity); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, nil)
+}
+
+// ApplyRoster 立即将名单写入 config.json（仅管理员）
+func ApplyRoster(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	changed, err := applyRoster()
+	if err != nil {
+		fail(c, "写入 config.json 失败: "+err.Error())
+		return
+	}
+	success(c, gin.H{"changed": changed})
+}
+
+// ExportRoster 导出名单为 CSV
+func ExportRoster(c *gin.Context) {
+	c.Header("Content-Disposition", "attachment; filename=arsm_roster.csv")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	w := csv.NewWriter(c.Writer)
+	w.Write(rosterCSVHeader)
+	for _, e := range loadRoster() {
+		expires := ""
+		if e.ExpiresAt > 0 {
+			expires = time.Unix(e.ExpiresAt, 0).UTC().Format(time.RFC3339)
+		}
+		w.Write([]string{e.Identity, e.Name, e.Note, strconv.FormatBool(e.Admin), expires})
+	}
+	w.Flush()
+}
+
+// parseRosterCSVRow 解析一行 CSV，expires_at 可以是 RFC3339、YYYY-MM-DD 或 Unix 时间戳
+func parseRosterCSVRow(columns map[string]int, row []string) (models.RosterEntry, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	e := models.RosterEntry{Identity: get("identity"), Name: get("name"), Note: get("note")}
+	if v := get("admin"); v != "" {
+		admin, err := strconv.ParseBool(v)
+		if err != nil {
+			return e, fmt.Errorf("admin 列应为 true / false: %s", v)
+		}
+		e.Admin = admin
+	}
+	if v := get("expires_at"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			e.ExpiresAt = ts
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			e.ExpiresAt = t.Unix()
+		} else if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			e.ExpiresAt = t.Unix()
+		} else {
+			return e, fmt.Errorf("无效的过期时间: %s", v)
+		}
+	}
+	return e, validateRosterEntry(&e)
+}
+
+// ImportRoster 从 CSV 导入名单（仅管理员）：按标识合并，replace=true 时替换整个名单
+func ImportRoster(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		fail(c, "未找到上传文件")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		fail(c, "打开文件失败")
+		return
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		fail(c, "CSV 文件为空或格式错误")
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["identity"]; !ok {
+		fail(c, "CSV 缺少 identity 列")
+		return
+	}
+
+	replace := c.PostForm("replace") == "true"
+	rosterMu.Lock()
+	defer rosterMu.Unlock()
+	roster := loadRoster()
+	var removed []string
+	if replace {
+		for _, e := range roster {
+			removed = append(removed, e.Identity)
+		}
+		roster = []models.RosterEntry{}
+	}
+
+	result := models.RosterImportResult{Errors: []string{}}
+	now := time.Now().Unix()
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("第 %d 行: %v", line, err))
+			continue
+		}
+		e, err := parseRosterCSVRow(columns, row)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("第 %d 行: %v", line, err))
+			continue
+		}
+		if i := findRosterEntry(roster, e.Identity); i >= 0 {
+			e.AddedBy, e.AddedAt = roster[i].AddedBy, roster[i].AddedAt
+			roster[i] = e
+			result.Updated++
+		} else {
+			e.AddedBy, e.AddedAt = currentUsername(c), now
+			roster = append(roster, e)
+			result.Added++
+		}
+	}
+	if err := saveAndApplyRoster(roster, removed...); err != nil {
+		fail(c, "保存失败: "+err.Error())
+		return
+	}
+	success(c, result)
+}
@@ -2,9 +2,13 @@ package api
 
 import (
 	"bufio"
+	"errors"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,34 +20,54 @@ import (
 
 var (
 	serverProcess *exec.Cmd
+	serverDone    chan struct{} // 进程退出时关闭
 	serverMu      sync.Mutex
 )
 
-// StartServer 启动服务端
-func StartServer(c *gin.Context) {
-	serverMu.Lock()
-	defer serverMu.Unlock()
+// 优雅停止的等待时间，超时后强制终止
+const serverStopTimeout = 3 * time.Second
 
-	if serverProcess != nil && serverProcess.Process != nil {
-		fail(c, "服务端已在运行")
-		return
-	}
+var (
+	errServerRunning    = errors.New("服务端已在运行")
+	errServerNotRunning = errors.New("服务端未运行")
+)
 
+// getServerExecutable 获取服务端可执行文件路径
+func getServerExecutable() string {
 	cfg := config.Get()
+	if runtime.GOOS == "windows" {
+		return filepath.Join(cfg.ServerPath, "ArmaReforgerServer.exe")
+	}
+	return filepath.Join(cfg.ServerPath, "ArmaReforgerServer")
+}
 
-	var executable string
-	var cmd *exec.Cmd
+// isServerManaged 服务端是否由 ARSM 启动并管理
+func isServerManaged() bool {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	return serverProcess != nil && serverProcess.Process != nil
+}
 
-	if runtime.GOOS == "windows" {
-		executable = filepath.Join(cfg.ServerPath, "ArmaReforgerServer.exe")
-	} else {
-		executable = filepath.Join(cfg.ServerPath, "ArmaReforgerServer")
+// startServerProcess 启动服务端进程，返回 PID
+func startServerProcess() (int, error) {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+
+	if serverProcess != nil && serverProcess.Process != nil {
+		return 0, errServerRunning
 	}
 
+	cfg := config.Get()
+	executable := getServerExecutable()
 	configPath := filepath.Join(cfg.ServerPath, "config.json")
 	profilePath := filepath.Join(cfg.ServerPath, "profile")
 
-	cmd = exec.Command(executable, "-config", configPath, "-profile", profilePath)
+	// 启动前写入受信任玩家名单，失败不影响启动
+	if _, err := applyRoster(); err != nil {
+		ws.Broadcast("[名单] 写入 config.json 失败: " + err.Error())
+	}
+
+	cmd := exec.Command(executable, "-config", configPath, "-profile", profilePath)
 	cmd.Dir = cfg.ServerPath
 
 	// Windows 隐藏控制台窗口
@@ -53,18 +77,22 @@ func StartServer(c *gin.Context) {
 	stderr, _ := cmd.StderrPipe()
 
 	if err := cmd.Start(); err != nil {
-		fail(c, "启动失败: "+err.Error())
-		return
+		return 0, err
 	}
 
+	done := make(chan struct{})
 	serverProcess = cmd
+	serverDone = done
 	ws.Broadcast("游戏服务端正在启动...")
+	gen := serverLife.begin()
+	go watchServerReady(gen)
 
 	// 异步读取 stdout
 	go func() {
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
 			ws.Broadcast(scanner.Text())
+			observeServerLog(gen, scanner.Text())
 		}
 	}()
 
@@ -73,61 +101,114 @@ func StartServer(c *gin.Context) {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
 			ws.Broadcast("SERVER ERROR: " + scanner.Text())
+			observeServerLog(gen, scanner.Text())
 		}
 	}()
 
 	// 异步等待进程结束
 	go func() {
-		cmd.Wait()
+		err := cmd.Wait()
 		serverMu.Lock()
-		serverProcess = nil
+		if serverProcess == cmd {
+			serverProcess = nil
+			serverDone = nil
+		}
 		serverMu.Unlock()
+		// 先更新状态再通知等待者，重启时 stopped 一定先于下一次 starting
+		serverExited(gen, err)
+		close(done)
 		ws.Broadcast("游戏服务端已停止。")
 	}()
 
-	success(c, map[string]int{"pid": cmd.Process.Pid})
+	return cmd.Process.Pid, nil
 }
 
-// StopServer 停止服务端
-func StopServer(c *gin.Context) {
+// stopServerProcess 停止服务端进程并等待其退出
+func stopServerProcess() error {
 	serverMu.Lock()
-	defer serverMu.Unlock()
-
 	if serverProcess == nil || serverProcess.Process == nil {
-		fail(c, "服务端未运行")
-		return
+		serverMu.Unlock()
+		return errServerNotRunning
 	}
+	proc := serverProcess.Process
+	done := serverDone
+	// 在发送信号前切换到 stopping，否则进程在此期间退出会被 serverExited 误判为崩溃
+	gen := serverLife.stopping()
+	serverMu.Unlock()
 
 	ws.Broadcast("正在停止游戏服务端...")
 
-	var err error
+	if err := signalServerStop(proc, done); err != nil {
+		serverLife.stopFailed(gen, done, err)
+		return err
+	}
+	return nil
+}
+
+// signalServerStop 发送停止信号并等待进程退出，超时后强制终止
+func signalServerStop(proc *os.Process, done <-chan struct{}) error {
 	if runtime.GOOS == "windows" {
-		// Windows: 先尝试优雅终止 (CTRL+C)，再强制终止
-		err = gracefulKillWindows(serverProcess.Process.Pid)
+		// Windows: 先尝试优雅终止，再强制终止
+		if err := gracefulKillWindows(proc.Pid); err != nil {
+			return err
+		}
 	} else {
 		// Linux: SIGTERM 优雅终止
-		err = serverProcess.Process.Signal(syscall.SIGTERM)
-		if err == nil {
-			// 等待 3 秒后检查是否终止
-			done := make(chan error, 1)
-			go func() {
-				done <- serverProcess.Wait()
-			}()
-			select {
-			case <-done:
-				// 已正常终止
-			case <-time.After(3 * time.Second):
-				// 超时，强制终止
-				serverProcess.Process.Kill()
-			}
+		if err := proc.Signal(syscall.SIGTERM); err != nil {
+			return err
 		}
 	}
 
+	select {
+	case <-done:
+		// 已正常终止
+	case <-time.After(serverStopTimeout):
+		// 超时，强制终止
+		proc.Kill()
+		select {
+		case <-done:
+		case <-time.After(serverStopTimeout):
+			return errors.New("等待进程退出超时")
+		}
+	}
+	return nil
+}
+
+// StartServer 启动服务端；wait=true 时等待服务端就绪后返回
+func StartServer(c *gin.Context) {
+	pid, err := startServerProcess()
 	if err != nil {
-		fail(c, "停止失败: "+err.Error())
+		if errors.Is(err, errServerRunning) {
+			fail(c, err.Error())
+		} else {
+			fail(c, "启动失败: "+err.Error())
+		}
+		return
+	}
+	wait, timeout := serverWaitTimeout(c)
+	if !wait {
+		success(c, map[string]int{"pid": pid})
+		return
+	}
+	event, err := waitForServerReady(timeout)
+	data := gin.H{"pid": pid, "state": event.State}
+	if err != nil {
+		failWithData(c, err.Error(), data)
 		return
 	}
+	success(c, data)
+}
 
+// StopServer 停止服务端
+func StopServer(c *gin.Context) {
+	if err := stopServerProcess(); err != nil {
+		if errors.Is(err, errServerNotRunning) {
+			fail(c, err.Error())
+		} else {
+			fail(c, "停止失败: "+err.Error())
+		}
+		return
+	}
 	success(c, nil)
 }
 
@@ -135,26 +216,10 @@ func StopServer(c *gin.Context) {
 func RestartServer(c *gin.Context) {
 	ws.Broadcast("正在重启游戏服务端...")
 
-	serverMu.Lock()
-	if serverProcess != nil && serverProcess.Process != nil {
-		if runtime.GOOS == "windows" {
-			gracefulKillWindows(serverProcess.Process.Pid)
-		} else {
-			serverProcess.Process.Signal(syscall.SIGTERM)
-			// 等待最多 3 秒
-			done := make(chan error, 1)
-			go func() {
-				done <- serverProcess.Wait()
-			}()
-			select {
-			case <-done:
-			case <-time.After(3 * time.Second):
-				serverProcess.Process.Kill()
-			}
-		}
-		serverProcess = nil
+	if err := stopServerProcess(); err != nil && !errors.Is(err, errServerNotRunning) {
+		fail(c, "停止失败: "+err.Error())
+		return
 	}
-	serverMu.Unlock()
 
 	// 等待一小段时间确保进程完全结束
 	time.Sleep(500 * time.Millisecond)
@@ -168,22 +233,23 @@ func gracefulKillWindows(pid int) error {
 	// 尝试发送 CTRL+C 信号（优雅终止）
 	// 但 Go 的 syscall 不支持直接发送 CTRL+C 到子进程
 	// 使用 taskkill 的 /T 参数终止进程树
-	cmd := exec.Command("taskkill", "/T", "/PID", string(rune(pid)))
+	pidStr := strconv.Itoa(pid)
+	cmd := exec.Command("taskkill", "/T", "/PID", pidStr)
 	err := cmd.Run()
 	if err != nil {
 		// 如果 taskkill 失败，强制终止
-		return exec.Command("taskkill", "/T", "/F", "/PID", string(rune(pid))).Run()
+		return exec.Command("taskkill", "/T", "/F", "/PID", pidStr).Run()
 	}
 
 	// 等待进程终止
 	time.Sleep(1 * time.Second)
 
 	// 检查进程是否还在运行
-	checkCmd := exec.Command("tasklist", "/FI", "PID eq "+string(rune(pid)))
+	checkCmd := exec.Command("tasklist", "/FI", "PID eq "+pidStr, "/NH")
 	out, _ := checkCmd.Output()
-	if string(out) != "" {
+	if strings.Contains(string(out), pidStr) {
 		// 进程还在，强制终止
-		return exec.Command("taskkill", "/T", "/F", "/PID", string(rune(pid))).Run()
+		return exec.Command("taskkill", "/T", "/F", "/PID", pidStr).Run()
 	}
 
 	return nil
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"arsm/a2s"
+	"arsm/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultA2SPort      = 17777
+	a2sQueryTimeout     = 2 * time.Second
+	a2sStatusTimeout    = time.Second
+	a2sStatusCacheValid = 5 * time.Second // 仪表盘轮询状态时复用的查询结果有效期
+)
+
+var (
+	a2sCacheMu sync.Mutex
+	a2sCache   *models.ServerQuery
+)
+
+// resolveA2SAddress 读取 config.json 中的 A2S 地址；未设置或监听所有地址时查询本机
+func resolveA2SAddress() string {
+	host, port := "", 0
+	if data, err := os.ReadFile(getConfigPath()); err == nil {
+		var serverConfig models.ServerConfig
+		if json.Unmarshal(data, &serverConfig) == nil {
+			host, port = serverConfig.A2S.Address, serverConfig.A2S.Port
+		}
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	if port <= 0 {
+		port = defaultA2SPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// queryServer 通过 A2S 查询服务端信息；withPlayers 为 true 时同时查询玩家列表（失败不影响结果）
+func queryServer(timeout time.Duration, withPlayers bool) models.ServerQuery {
+	result := models.ServerQuery{Address: resolveA2SAddress(), QueriedAt: time.Now().Unix()}
+	client, err := a2s.Dial(result.Address, timeout)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer client.Close()
+
+	start := time.Now()
+	info, err := client.Info()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Online = true
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Info = serverInfoFromA2S(info)
+	if withPlayers {
+		if players, err := client.Players(); err != nil {
+			result.Error = fmt.Sprintf("玩家列表查询失败: %v", err)
+		} else {
+			result.Players = serverPlayersFromA2S(players)
+		}
+	}
+	return result
+}
+
+// serverInfoFromA2S 转换为接口返回的服务端信息
+func serverInfoFromA2S(info *a2s.Info) *models.ServerInfo {
+	return &models.ServerInfo{
+		Protocol:    info.Protocol,
+		Name:        info.Name,
+		Map:         info.Map,
+		Folder:      info.Folder,
+		Game:        info.Game,
+		AppID:       info.AppID,
+		Players:     info.Players,
+		MaxPlayers:  info.MaxPlayers,
+		Bots:        info.Bots,
+		ServerType:  info.ServerType,
+		Environment: info.Environment,
+		Password:    info.Password,
+		VAC:         info.VAC,
+		Version:     info.Version,
+		Port:        info.Port,
+		SteamID:     info.SteamID,
+		Keywords:    info.Keywords,
+		GameID:      info.GameID,
+	}
+}
+
+// serverPlayersFromA2S 转换为接口返回的玩家列表
+func serverPlayersFromA2S(players []a2s.Player) []models.ServerPlayer {
+	result := make([]models.ServerPlayer, 0, len(players))
+	for _, p := range players {
+		result = append(result, models.ServerPlayer{Index: p.Index, Name: p.Name, Score: p.Score, Duration: p.Duration})
+	}
+	return result
+}
+
+// cachedServerQuery 返回短时间内的查询结果，避免频繁轮询状态时重复查询
+func cachedServerQuery() models.ServerQuery {
+	a2sCacheMu.Lock()
+	if a2sCache != nil && time.Since(time.Unix(a2sCache.QueriedAt, 0)) < a2sStatusCacheValid {
+		result := *a2sCache
+		a2sCacheMu.Unlock()
+		return result
+	}
+	a2sCacheMu.Unlock()
+	result := queryServer(a2sStatusTimeout, false)
+	storeServerQuery(result)
+	return result
+}
+
+// storeServerQuery 缓存查询结果，不含玩家列表
+func storeServerQuery(result models.ServerQuery) {
+	result.Players = nil
+	a2sCacheMu.Lock()
+	a2sCache = &result
+	a2sCacheMu.Unlock()
+}
+
+// GetServerQuery 通过 A2S 查询服务端公布的名称、地图、人数和玩家列表
+func GetServerQuery(c *gin.Context) {
+	result := queryServer(a2sQueryTimeout, true)
+	storeServerQuery(result)
+	success(c, result)
+}
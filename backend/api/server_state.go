@@ -0,0 +1,229 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"arsm/models"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+)
+
+// 服务端生命周期状态
+const (
+	serverStateStopped  = "stopped"
+	serverStateStarting = "starting" // 进程已启动，尚未开始加载世界
+	serverStateLoading  = "loading"  // 正在加载世界
+	serverStateReady    = "ready"    // 可以接受玩家
+	serverStateStopping = "stopping"
+	serverStateCrashed  = "crashed" // 进程非 ARSM 停止而退出
+)
+
+const (
+	serverReadyPollInterval = 5 * time.Second // 加载期间通过 A2S 确认就绪的间隔
+	defaultServerWaitTime   = 10 * time.Minute
+	maxServerWaitTime       = 30 * time.Minute
+)
+
+// 控制台输出中标志加载阶段的日志
+var (
+	reServerLoading = regexp.MustCompile(`(?i)loading world|world loading|opening world|loading mission|game load`)
+	reServerReady   = regexp.MustCompile(`(?i)entered online game state|game successfully created`)
+)
+
+// serverLifecycle 由 ARSM 管理的服务端进程的状态
+type serverLifecycle struct {
+	mu      sync.Mutex
+	state   string
+	since   time.Time
+	detail  string
+	gen     int           // 每次启动递增，用于丢弃上一次进程的事件
+	changed chan struct{} // 状态变化时关闭并替换，用于等待
+}
+
+var (
+	serverLife     = &serverLifecycle{state: serverStateStopped, since: time.Now(), changed: make(chan struct{})}
+	serverStateHub = ws.NewHub()
+)
+
+// event 当前状态的推送消息
+func (l *serverLifecycle) event(previous string) models.ServerStateEvent {
+	return models.ServerStateEvent{State: l.state, Previous: previous, Detail: l.detail, Since: l.since.Unix()}
+}
+
+// transition 切换状态并推送；gen 不是当前启动或 from 不包含当前状态时忽略（from 为空表示不限制）
+func (l *serverLifecycle) transition(gen int, to, detail string, from ...string) bool {
+	l.mu.Lock()
+	if gen != l.gen || l.state == to {
+		l.mu.Unlock()
+		return false
+	}
+	if len(from) > 0 {
+		allowed := false
+		for _, s := range from {
+			if s == l.state {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			l.mu.Unlock()
+			return false
+		}
+	}
+	previous := l.state
+	l.state, l.detail, l.since = to, detail, time.Now()
+	close(l.changed)
+	l.changed = make(chan struct{})
+	event := l.event(previous)
+	l.mu.Unlock()
+
+	serverStateHub.BroadcastJSON(event)
+	msg := "[状态] " + previous + " -> " + to
+	if detail != "" {
+		msg += ": " + detail
+	}
+	ws.Broadcast(msg)
+	return true
+}
+
+// begin 新进程启动，返回本次启动的编号
+func (l *serverLifecycle) begin() int {
+	l.mu.Lock()
+	l.gen++
+	gen := l.gen
+	l.mu.Unlock()
+	l.transition(gen, serverStateStarting, "")
+	return gen
+}
+
+// stopping ARSM 开始停止进程，返回本次启动的编号
+func (l *serverLifecycle) stopping() int {
+	l.mu.Lock()
+	gen := l.gen
+	l.mu.Unlock()
+	l.transition(gen, serverStateStopping, "")
+	return gen
+}
+
+// stopFailed 停止失败时按进程实际情况恢复状态：已退出的由 serverExited 处理，
+// 仍在运行的按 A2S 结果恢复为 ready 或 loading
+func (l *serverLifecycle) stopFailed(gen int, done <-chan struct{}, err error) {
+	select {
+	case <-done:
+		return
+	default:
+	}
+	to := serverStateLoading
+	if query := queryServer(a2sStatusTimeout, false); query.Online {
+		to = serverStateReady
+	}
+	if l.transition(gen, to, "停止失败: "+err.Error(), serverStateStopping) && to == serverStateLoading {
+		go watchServerReady(gen)
+	}
+}
+
+// current 返回当前状态、编号和状态变化通知
+func (l *serverLifecycle) current() (models.ServerStateEvent, int, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.event(""), l.gen, l.changed
+}
+
+// observeServerLog 根据控制台输出推进状态
+func observeServerLog(gen int, line string) {
+	switch {
+	case reServerReady.MatchString(line):
+		serverLife.transition(gen, serverStateReady, "日志: "+line, serverStateStarting, serverStateLoading)
+	case reServerLoading.MatchString(line):
+		serverLife.transition(gen, serverStateLoading, "", serverStateStarting)
+	}
+}
+
+// watchServerReady 加载期间定期通过 A2S 查询确认就绪，日志格式变化时仍能识别
+func watchServerReady(gen int) {
+	for {
+		time.Sleep(serverReadyPollInterval)
+		state, current, _ := serverLife.current()
+		if current != gen || (state.State != serverStateStarting && state.State != serverStateLoading) {
+			return
+		}
+		if query := queryServer(a2sStatusTimeout, false); query.Online {
+			serverLife.transition(gen, serverStateReady, "A2S 查询已响应", serverStateStarting, serverStateLoading)
+			return
+		}
+	}
+}
+
+// markReady 其他途径（如状态查询）确认 A2S 已响应时直接标记就绪
+func (l *serverLifecycle) markReady() {
+	l.mu.Lock()
+	gen := l.gen
+	l.mu.Unlock()
+	l.transition(gen, serverStateReady, "A2S 查询已响应", serverStateStarting, serverStateLoading)
+}
+
+// serverExited 进程退出：ARSM 停止的为 stopped，否则为 crashed
+func serverExited(gen int, err error) {
+	if serverLife.transition(gen, serverStateStopped, "", serverStateStopping) {
+		return
+	}
+	detail := "进程意外退出"
+	if err != nil {
+		detail += ": " + err.Error()
+	}
+	serverLife.transition(gen, serverStateCrashed, detail)
+}
+
+// serverStatusState 状态接口使用的状态；非 ARSM 启动的进程按 A2S 结果推断
+func serverStatusState(running, ready bool) models.ServerStateEvent {
+	event, _, _ := serverLife.current()
+	if running && !isServerManaged() {
+		event = models.ServerStateEvent{State: serverStateLoading}
+		if ready {
+			event.State = serverStateReady
+		}
+	}
+	return event
+}
+
+// waitForServerReady 等待服务端就绪；崩溃、停止或超时时返回错误
+func waitForServerReady(timeout time.Duration) (models.ServerStateEvent, error) {
+	deadline := time.After(timeout)
+	for {
+		event, _, changed := serverLife.current()
+		switch event.State {
+		case serverStateReady:
+			return event, nil
+		case serverStateCrashed, serverStateStopped:
+			return event, fmt.Errorf("服务端未能就绪（%s）%s", event.State, event.Detail)
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return event, fmt.Errorf("等待服务端就绪超时，当前状态: %s", event.State)
+		}
+	}
+}
+
+// serverWaitTimeout 解析 wait / timeout 参数，返回是否等待及等待时长
+func serverWaitTimeout(c *gin.Context) (bool, time.Duration) {
+	if c.Query("wait") != "true" {
+		return false, 0
+	}
+	seconds := queryInt(c, "timeout", int(defaultServerWaitTime/time.Second), int(maxServerWaitTime/time.Second))
+	return true, time.Duration(seconds) * time.Second
+}
+
+// HandleServerState WebSocket 推送服务端状态变化，连接后先发送当前状态
+func HandleServerState(c *gin.Context) {
+	if !wsAuthorized(c) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "无效的认证令牌"})
+		return
+	}
+	event, _, _ := serverLife.current()
+	serverStateHub.Handle(c, []interface{}{event})
+}
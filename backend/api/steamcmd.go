@@ -2,6 +2,7 @@ package api
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -54,14 +55,7 @@ func GetSteamCMDStatus(c *gin.Context) {
 		Path:      cfg.SteamCMDPath,
 	}
 
-	var executable string
-	if runtime.GOOS == "windows" {
-		executable = filepath.Join(cfg.SteamCMDPath, "steamcmd.exe")
-	} else {
-		executable = filepath.Join(cfg.SteamCMDPath, "steamcmd.sh")
-	}
-
-	if _, err := os.Stat(executable); err == nil {
+	if _, err := os.Stat(getSteamCMDExecutable()); err == nil {
 		status.Installed = true
 	}
 
@@ -70,43 +64,8 @@ func GetSteamCMDStatus(c *gin.Context) {
 
 // InstallSteamCMD 安装SteamCMD
 func InstallSteamCMD(c *gin.Context) {
-	cfg := config.Get()
-
-	// 创建目录
-	var mkdirErr error
-	if runtime.GOOS == "windows" {
-		mkdirErr = os.MkdirAll(cfg.SteamCMDPath, 0755)
-	} else {
-		mkdirErr = os.MkdirAll(cfg.SteamCMDPath, 0755)
-	}
-	if mkdirErr != nil {
-		fail(c, "创建目录失败: "+mkdirErr.Error())
-		return
-	}
-
-	var cmd *exec.Cmd
-
-	if runtime.GOOS == "windows" {
-		ws.Broadcast("开始下载 SteamCMD (Windows)...")
-		// 使用 -ExecutionPolicy Bypass 绕过策略限制
-		// 使用 -UseBasicParsing 兼容没有 IE 的环境
-		script := `$ProgressPreference = 'SilentlyContinue'
-$url = "https://steamcdn-a.akamaihd.net/client/installer/steamcmd.zip"
-$zip = "$env:TEMP\steamcmd.zip"
-Invoke-WebRequest -Uri $url -OutFile $zip -UseBasicParsing
-Expand-Archive -Path $zip -DestinationPath "` + cfg.SteamCMDPath + `" -Force
-Remove-Item $zip`
-		cmd = exec.Command("powershell", "-ExecutionPolicy", "Bypass", "-Command", script)
-		hideWindow(cmd)
-	} else {
-		ws.Broadcast("开始下载 SteamCMD (Linux)...")
-		script := `cd "` + cfg.SteamCMDPath + `" && \
-curl -sqL "https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz" | tar zxvf - && \
-chmod +x steamcmd.sh`
-		cmd = exec.Command("bash", "-c", script)
-	}
-
-	if err := execAndStream(cmd); err != nil {
+	if err := installSteamCMD(); err != nil {
+		ws.Broadcast("SteamCMD 安装失败: " + err.Error())
 		fail(c, "安装失败: "+err.Error())
 		return
 	}
@@ -156,26 +115,33 @@ func DeleteSteamCMD(c *gin.Context) {
 
 // GetServerStatus 获取服务端状态
 func GetServerStatus(c *gin.Context) {
-	cfg := config.Get()
 	status := models.ServerStatus{
 		Installed: false,
 		Running:   false,
 	}
 
-	var executable string
-	if runtime.GOOS == "windows" {
-		executable = filepath.Join(cfg.ServerPath, "ArmaReforgerServer.exe")
-	} else {
-		executable = filepath.Join(cfg.ServerPath, "ArmaReforgerServer")
-	}
-
-	if _, err := os.Stat(executable); err == nil {
+	if _, err := os.Stat(getServerExecutable()); err == nil {
 		status.Installed = true
 	}
 
 	// 检查进程是否运行
 	status.Running = isProcessRunning()
 
+	// 进程运行时通过 A2S 判断是否已可以接受玩家
+	online := false
+	if status.Running {
+		query := cachedServerQuery()
+		query.Players = nil
+		online = query.Online
+		status.Query = &query
+		if online {
+			serverLife.markReady()
+		}
+	}
+	state := serverStatusState(status.Running, online)
+	status.State, status.StateInfo, status.Since = state.State, state.Detail, state.Since
+	status.Ready = state.State == serverStateReady
+
 	success(c, status)
 }
 
@@ -232,48 +198,59 @@ func getProcessPID() int {
 	}
 }
 
-// InstallServer 安装游戏服务端
-func InstallServer(c *gin.Context) {
+// getSteamCMDExecutable 获取 SteamCMD 可执行文件路径
+func getSteamCMDExecutable() string {
+	cfg := config.Get()
+	if runtime.GOOS == "windows" {
+		return filepath.Join(cfg.SteamCMDPath, "steamcmd.exe")
+	}
+	return filepath.Join(cfg.SteamCMDPath, "steamcmd.sh")
+}
+
+// runServerAppUpdate 使用 SteamCMD 安装/更新服务端文件
+func runServerAppUpdate() error {
 	cfg := config.Get()
 	ws.Broadcast("开始安装/更新 Arma Reforger 服务端 (AppID 1874900)...")
 
 	// 创建目录
 	if err := os.MkdirAll(cfg.ServerPath, 0755); err != nil {
-		fail(c, "创建目录失败: "+err.Error())
-		return
-	}
-
-	var steamcmd string
-	if runtime.GOOS == "windows" {
-		steamcmd = filepath.Join(cfg.SteamCMDPath, "steamcmd.exe")
-	} else {
-		steamcmd = filepath.Join(cfg.SteamCMDPath, "steamcmd.sh")
+		return fmt.Errorf("创建目录失败: %w", err)
 	}
 
 	cmd := exec.Command(
-		steamcmd,
+		getSteamCMDExecutable(),
 		"+force_install_dir", cfg.ServerPath,
 		"+login", "anonymous",
 		"+app_update", "1874900", "validate",
 		"+quit",
 	)
-
-	if runtime.GOOS == "windows" {
-		hideWindow(cmd)
-	}
+	hideWindow(cmd)
 
 	if err := execAndStream(cmd); err != nil {
-		fail(c, "安装失败: "+err.Error())
-		return
+		return fmt.Errorf("安装失败: %w", err)
 	}
 
 	ws.Broadcast("服务端安装/更新完成。")
-	success(c, nil)
+	return nil
 }
 
-// UpdateServer 更新服务端
-func UpdateServer(c *gin.Context) {
-	InstallServer(c)
+// InstallServer 安装游戏服务端
+func InstallServer(c *gin.Context) {
+	// 与安全更新互斥，运行中的服务端需通过 /api/server/update 更新
+	if !updateMu.TryLock() {
+		fail(c, "已有更新任务正在进行")
+		return
+	}
+	defer updateMu.Unlock()
+	if isProcessRunning() {
+		fail(c, "服务端正在运行，请先停止或使用安全更新")
+		return
+	}
+	if err := runServerAppUpdate(); err != nil {
+		fail(c, err.Error())
+		return
+	}
+	success(c, nil)
 }
 
 // DeleteServer 删除服务端
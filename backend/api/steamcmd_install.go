@@ -0,0 +1,253 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"arsm/config"
+	"arsm/ws"
+)
+
+// SteamCMD 安装包大小上限，防止异常响应写满磁盘
+const maxSteamCMDArchiveSize = 64 << 20
+
+// steamCMDArchiveName 当前平台对应的安装包文件名
+func steamCMDArchiveName() string {
+	if runtime.GOOS == "windows" {
+		return "steamcmd.zip"
+	}
+	return "steamcmd_linux.tar.gz"
+}
+
+// steamCMDDownloadURL 根据配置的下载源拼接安装包地址
+func steamCMDDownloadURL() string {
+	mirror := config.Get().SteamCMDMirror
+	if mirror == "" {
+		mirror = config.DefaultSteamCMDMirror
+	}
+	return strings.TrimRight(mirror, "/") + "/" + steamCMDArchiveName()
+}
+
+// progressWriter 统计写入字节数并按 10% 的粒度广播下载进度
+type progressWriter struct {
+	total   int64
+	written int64
+	lastPct int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.total > 0 {
+		pct := p.written * 100 / p.total
+		if pct/10 > p.lastPct/10 {
+			p.lastPct = pct
+			ws.Broadcast(fmt.Sprintf("下载 SteamCMD: %d%% (%d/%d 字节)", pct, p.written, p.total))
+		}
+	}
+	return len(b), nil
+}
+
+// downloadSteamCMDArchive 下载安装包到 dst，校验大小和可选的 SHA-256
+func downloadSteamCMDArchive(url string, dst io.Writer, expectSHA256 string) error {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSteamCMDArchiveSize {
+		return fmt.Errorf("安装包过大: %d 字节", resp.ContentLength)
+	}
+
+	hash := sha256.New()
+	progress := &progressWriter{total: resp.ContentLength}
+	body := io.LimitReader(resp.Body, maxSteamCMDArchiveSize+1)
+	n, err := io.Copy(io.MultiWriter(dst, hash, progress), body)
+	if err != nil {
+		return fmt.Errorf("下载中断: %w", err)
+	}
+	if n > maxSteamCMDArchiveSize {
+		return errors.New("安装包超过大小上限")
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("文件大小不匹配: 期望 %d 字节, 实际 %d 字节", resp.ContentLength, n)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if expectSHA256 != "" && !strings.EqualFold(sum, strings.TrimSpace(expectSHA256)) {
+		return fmt.Errorf("SHA-256 校验失败: %s", sum)
+	}
+	ws.Broadcast(fmt.Sprintf("下载完成: %d 字节, SHA-256 %s", n, sum))
+	return nil
+}
+
+// safeJoin 将归档内路径拼接到目标目录，拒绝绝对路径和 ../ 穿越
+func safeJoin(dest, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("非法路径: %s", name)
+	}
+	target := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("非法路径: %s", name)
+	}
+	return target, nil
+}
+
+// writeExtractedFile 写出归档中的单个文件
+func writeExtractedFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// OpenFile 的权限受 umask 影响，且不会修改已存在文件的权限
+	return os.Chmod(target, mode)
+}
+
+// extractTarGz 解压 tar.gz 到 dest
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			mode := os.FileMode(hdr.Mode).Perm() | 0600
+			if err := writeExtractedFile(target, tr, mode); err != nil {
+				return err
+			}
+		default:
+			// 忽略符号链接、硬链接、设备文件等，避免借助链接写到目标目录之外
+		}
+	}
+}
+
+// extractZip 解压 zip 到 dest
+func extractZip(path, dest string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := safeJoin(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtractedFile(target, rc, f.Mode().Perm()|0600)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installSteamCMD 下载并解压 SteamCMD 到配置的目录
+func installSteamCMD() error {
+	cfg := config.Get()
+	if err := os.MkdirAll(cfg.SteamCMDPath, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	url := steamCMDDownloadURL()
+	ws.Broadcast("开始下载 SteamCMD: " + url)
+
+	tmp, err := os.CreateTemp("", "arsm-"+steamCMDArchiveName())
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := downloadSteamCMDArchive(url, tmp, cfg.SteamCMDSHA256); err != nil {
+		return err
+	}
+
+	ws.Broadcast("正在解压 SteamCMD...")
+	if runtime.GOOS == "windows" {
+		tmp.Close()
+		err = extractZip(tmp.Name(), cfg.SteamCMDPath)
+	} else {
+		if _, err = tmp.Seek(0, io.SeekStart); err == nil {
+			err = extractTarGz(tmp, cfg.SteamCMDPath)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("解压失败: %w", err)
+	}
+
+	// 确保启动脚本可执行
+	executable := getSteamCMDExecutable()
+	if _, err := os.Stat(executable); err != nil {
+		return errors.New("安装包中未找到 SteamCMD 可执行文件")
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(executable, 0755); err != nil {
+			return fmt.Errorf("设置可执行权限失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"arsm/config"
+)
+
+type archiveEntry struct {
+	name string
+	body string
+	mode int64
+	dir  bool
+}
+
+func buildTarGz(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.dir {
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if !e.dir {
+			tw.Write([]byte(e.body))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []archiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name}
+		hdr.SetMode(os.FileMode(e.mode))
+		if e.dir {
+			hdr.Name = strings.TrimSuffix(e.name, "/") + "/"
+			hdr.SetMode(os.ModeDir | 0755)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !e.dir {
+			w.Write([]byte(e.body))
+		}
+	}
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "steamcmd.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSteamCMDDownloadURLMirror(t *testing.T) {
+	withConfig(t, func(cfg *config.AppConfig) { cfg.SteamCMDMirror = "" })
+	if got, want := steamCMDDownloadURL(), config.DefaultSteamCMDMirror+steamCMDArchiveName(); got != want {
+		t.Errorf("default url = %q, want %q", got, want)
+	}
+	withConfig(t, func(cfg *config.AppConfig) { cfg.SteamCMDMirror = "http://mirror.local/steam//" })
+	if got, want := steamCMDDownloadURL(), "http://mirror.local/steam/"+steamCMDArchiveName(); got != want {
+		t.Errorf("mirror url = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadSteamCMDArchive(t *testing.T) {
+	payload := []byte("steamcmd archive payload")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write(payload)
+		case "/short":
+			// 声明的长度大于实际发送的内容
+			w.Header().Set("Content-Length", strconv.Itoa(len(payload)+10))
+			w.Write(payload)
+		case "/huge":
+			w.Header().Set("Content-Length", strconv.Itoa(maxSteamCMDArchiveSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		sha     string
+		wantErr string
+	}{
+		{"ok without checksum", "/ok", "", ""},
+		{"ok with checksum", "/ok", strings.ToUpper(sha256Hex(payload)), ""},
+		{"checksum mismatch", "/ok", sha256Hex([]byte("other")), "SHA-256"},
+		{"content length mismatch", "/short", "", "下载"},
+		{"too large", "/huge", "", "过大"},
+		{"not found", "/missing", "", "HTTP 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := downloadSteamCMDArchive(srv.URL+tt.path, &buf, tt.sha)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !bytes.Equal(buf.Bytes(), payload) {
+					t.Fatalf("body = %q", buf.Bytes())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	dest := t.TempDir()
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"steamcmd.sh", true},
+		{"linux32/steamcmd", true},
+		{"a/../b", true},
+		{"../escape", false},
+		{"a/../../escape", false},
+		{"..", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		target, err := safeJoin(dest, tt.name)
+		if tt.ok {
+			if err != nil || !strings.HasPrefix(target, dest) {
+				t.Errorf("safeJoin(%q) = %q, %v", tt.name, target, err)
+			}
+		} else if err == nil {
+			t.Errorf("safeJoin(%q) = %q, want error", tt.name, target)
+		}
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dest := t.TempDir()
+	data := buildTarGz(t, []archiveEntry{
+		{name: "linux32", dir: true},
+		{name: "linux32/steamcmd", body: "elf", mode: 0755},
+		{name: "steamcmd.sh", body: "#!/bin/sh", mode: 0644},
+	})
+	if err := extractTarGz(bytes.NewReader(data), dest); err != nil {
+		t.Fatal(err)
+	}
+	if body, err := os.ReadFile(filepath.Join(dest, "linux32", "steamcmd")); err != nil || string(body) != "elf" {
+		t.Fatalf("linux32/steamcmd = %q, %v", body, err)
+	}
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(filepath.Join(dest, "linux32", "steamcmd"))
+		if info.Mode().Perm()&0100 == 0 {
+			t.Errorf("linux32/steamcmd mode = %v, want executable", info.Mode())
+		}
+	}
+
+	for _, name := range []string{"../evil.sh", "/tmp/evil.sh"} {
+		data := buildTarGz(t, []archiveEntry{{name: name, body: "x", mode: 0644}})
+		if err := extractTarGz(bytes.NewReader(data), t.TempDir()); err == nil {
+			t.Errorf("extractTarGz accepted %q", name)
+		}
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	dest := t.TempDir()
+	path := buildZip(t, []archiveEntry{
+		{name: "package", dir: true},
+		{name: "package/steam.pkg", body: "pkg", mode: 0644},
+		{name: "steamcmd.exe", body: "MZ", mode: 0644},
+	})
+	if err := extractZip(path, dest); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"steamcmd.exe": "MZ", "package/steam.pkg": "pkg"} {
+		if body, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name))); err != nil || string(body) != want {
+			t.Errorf("%s = %q, %v", name, body, err)
+		}
+	}
+
+	for _, name := range []string{"../evil.exe", "/tmp/evil.exe"} {
+		path := buildZip(t, []archiveEntry{{name: name, body: "x", mode: 0644}})
+		if err := extractZip(path, t.TempDir()); err == nil {
+			t.Errorf("extractZip accepted %q", name)
+		}
+	}
+}
+
+func TestInstallSteamCMDFromMirror(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("测试使用 Linux 安装包")
+	}
+	archive := buildTarGz(t, []archiveEntry{
+		{name: "steamcmd.sh", body: "#!/bin/sh\n", mode: 0644},
+		{name: "linux32/steamcmd", body: "elf", mode: 0755},
+	})
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	steamCMDPath := filepath.Join(t.TempDir(), "steamcmd")
+	withConfig(t, func(cfg *config.AppConfig) {
+		cfg.SteamCMDPath = steamCMDPath
+		cfg.SteamCMDMirror = srv.URL + "/mirror"
+		cfg.SteamCMDSHA256 = sha256Hex(archive)
+	})
+
+	if err := installSteamCMD(); err != nil {
+		t.Fatal(err)
+	}
+	if requested != "/mirror/steamcmd_linux.tar.gz" {
+		t.Errorf("requested %q", requested)
+	}
+	info, err := os.Stat(filepath.Join(steamCMDPath, "steamcmd.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("steamcmd.sh mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	withConfig(t, func(cfg *config.AppConfig) { cfg.SteamCMDSHA256 = sha256Hex([]byte("tampered")) })
+	if err := installSteamCMD(); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("install with wrong checksum: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"arsm/config"
+	"arsm/ws"
+	"github.com/gin-gonic/gin"
+)
+
+// 默认的更新前玩家提醒
+const (
+	defaultUpdateWarnMessage = "服务器即将停机更新，请稍后重新连接"
+	defaultUpdateWarnDelay   = 10 // 秒
+)
+
+// 同一时间只允许一个更新流程
+var updateMu sync.Mutex
+
+// 最近一次更新流程的进度，step 与 GetServerUpdate 并发访问
+var (
+	updateStatusMu sync.Mutex
+	lastUpdate     *UpdateResult
+)
+
+// appmanifest 中的安装状态标志（EAppState）
+const (
+	appStateUpdateRequired = 0x2
+	appStateFullyInstalled = 0x4
+	appStateFilesMissing   = 0x20
+	appStateFilesCorrupt   = 0x80
+	appStateUpdateRunning  = 0x100
+	appStateUpdatePaused   = 0x200
+	appStateUpdateStarted  = 0x400
+	appStateIncomplete     = appStateUpdateRequired | appStateFilesMissing | appStateFilesCorrupt |
+		appStateUpdateRunning | appStateUpdatePaused | appStateUpdateStarted
+)
+
+var (
+	reManifestBuildID    = regexp.MustCompile(`(?i)"buildid"\s+"(\d+)"`)
+	reManifestStateFlags = regexp.MustCompile(`(?i)"StateFlags"\s+"(\d+)"`)
+)
+
+// UpdateStep 更新流程中的单个步骤结果
+type UpdateStep struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// UpdateResult 更新流程结果
+type UpdateResult struct {
+	Running    bool         `json:"running"`
+	WasRunning bool         `json:"was_running"`
+	Updated    bool         `json:"updated"`
+	Restarted  bool         `json:"restarted"`
+	OldBuild   string       `json:"old_build,omitempty"`
+	NewBuild   string       `json:"new_build,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	StartedAt  int64        `json:"started_at"`
+	FinishedAt int64        `json:"finished_at,omitempty"`
+	Steps      []UpdateStep `json:"steps"`
+}
+
+// step 记录步骤并广播到日志
+func (r *UpdateResult) step(name string, err error, message string) {
+	s := UpdateStep{Name: name, OK: err == nil, Message: message}
+	if err != nil {
+		s.Message = err.Error()
+		ws.Broadcast(fmt.Sprintf("[更新] %s 失败: %s", name, s.Message))
+	} else if message != "" {
+		ws.Broadcast(fmt.Sprintf("[更新] %s 完成: %s", name, message))
+	} else {
+		ws.Broadcast(fmt.Sprintf("[更新] %s 完成", name))
+	}
+	updateStatusMu.Lock()
+	r.Steps = append(r.Steps, s)
+	updateStatusMu.Unlock()
+}
+
+// update 在锁内修改结果，供 GetServerUpdate 读取一致的快照
+func (r *UpdateResult) update(fn func(r *UpdateResult)) {
+	updateStatusMu.Lock()
+	fn(r)
+	updateStatusMu.Unlock()
+}
+
+// warnPlayers 通过 RCON 向玩家广播更新提醒
+func warnPlayers(message string) error {
+	command := "say -1 " + message
+	_, err := rconExecAs(rconSystemUser, command)
+	return err
+}
+
+// appManifest SteamCMD 记录的服务端安装状态
+type appManifest struct {
+	BuildID    string
+	StateFlags int
+}
+
+// readAppManifest 读取 steamapps/appmanifest_1874900.acf
+func readAppManifest() (*appManifest, error) {
+	data, err := os.ReadFile(filepath.Join(config.Get().ServerPath, "steamapps", "appmanifest_1874900.acf"))
+	if err != nil {
+		return nil, err
+	}
+	m := &appManifest{}
+	if match := reManifestBuildID.FindSubmatch(data); match != nil {
+		m.BuildID = string(match[1])
+	}
+	match := reManifestStateFlags.FindSubmatch(data)
+	if match == nil {
+		return nil, errors.New("appmanifest 缺少 StateFlags")
+	}
+	m.StateFlags, _ = strconv.Atoi(string(match[1]))
+	return m, nil
+}
+
+// verifyServerInstall 检查服务端文件是否完整可用；requireManifest 为 true 时还要求 SteamCMD 记录为完整安装
+func verifyServerInstall(requireManifest bool) (*appManifest, error) {
+	info, err := os.Stat(getServerExecutable())
+	if err != nil {
+		return nil, errors.New("未找到服务端可执行文件")
+	}
+	if info.Size() == 0 {
+		return nil, errors.New("服务端可执行文件为空")
+	}
+	manifest, err := readAppManifest()
+	if err != nil {
+		if requireManifest {
+			return nil, fmt.Errorf("读取 appmanifest 失败: %w", err)
+		}
+		return nil, nil
+	}
+	if manifest.StateFlags&appStateFullyInstalled == 0 || manifest.StateFlags&appStateIncomplete != 0 {
+		return manifest, fmt.Errorf("安装不完整（StateFlags=%d）", manifest.StateFlags)
+	}
+	return manifest, nil
+}
+
+// UpdateServer 安全更新服务端：提醒玩家、停止、更新、校验、按需重启；流程在后台执行，进度通过日志推送
+func UpdateServer(c *gin.Context) {
+	var req struct {
+		WarnMessage string `json:"warn_message"`
+		WarnDelay   *int   `json:"warn_delay"` // 提醒后等待的秒数
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			fail(c, "无效的请求数据")
+			return
+		}
+	}
+	if req.WarnMessage == "" {
+		req.WarnMessage = defaultUpdateWarnMessage
+	}
+	warnDelay := defaultUpdateWarnDelay
+	if req.WarnDelay != nil && *req.WarnDelay >= 0 {
+		warnDelay = *req.WarnDelay
+	}
+
+	if !updateMu.TryLock() {
+		fail(c, "已有更新任务正在进行")
+		return
+	}
+
+	result := &UpdateResult{Running: true, WasRunning: isServerManaged(), StartedAt: time.Now().Unix(), Steps: []UpdateStep{}}
+
+	// 由外部启动的进程无法通过 ARSM 停止，直接更新会损坏文件
+	if !result.WasRunning && isProcessRunning() {
+		updateMu.Unlock()
+		fail(c, "服务端不是由 ARSM 启动的，请先手动停止后再更新")
+		return
+	}
+
+	updateStatusMu.Lock()
+	lastUpdate = result
+	updateStatusMu.Unlock()
+
+	go func() {
+		defer updateMu.Unlock()
+		runSafeUpdate(result, req.WarnMessage, time.Duration(warnDelay)*time.Second)
+	}()
+	success(c, snapshotUpdate())
+}
+
+// runSafeUpdate 执行更新流程
+func runSafeUpdate(result *UpdateResult, warnMessage string, warnDelay time.Duration) {
+	ws.Broadcast("[更新] 开始安全更新流程...")
+
+	var updateErr error
+	defer func() {
+		result.update(func(r *UpdateResult) {
+			r.Running = false
+			r.FinishedAt = time.Now().Unix()
+			if updateErr != nil {
+				r.Error = updateErr.Error()
+			}
+		})
+		if updateErr != nil {
+			ws.Broadcast("[更新] 更新失败: " + updateErr.Error())
+		} else {
+			ws.Broadcast("[更新] 安全更新流程完成。")
+		}
+	}()
+
+	// 更新前的安装状态，用于判断失败后文件是否仍可用
+	before, beforeErr := verifyServerInstall(false)
+	hadManifest := before != nil
+	if before != nil {
+		result.update(func(r *UpdateResult) { r.OldBuild = before.BuildID })
+	}
+
+	if result.WasRunning {
+		// 1. 提醒玩家（失败不影响后续步骤）
+		if err := warnPlayers(warnMessage); err != nil {
+			result.step("提醒玩家", err, "")
+		} else {
+			result.step("提醒玩家", nil, warnMessage)
+			time.Sleep(warnDelay)
+		}
+
+		// 2. 停止服务端
+		if err := stopServerProcess(); err != nil && !errors.Is(err, errServerNotRunning) {
+			result.step("停止服务端", err, "")
+			updateErr = fmt.Errorf("停止服务端失败，已取消更新: %w", err)
+			return
+		}
+		result.step("停止服务端", nil, "")
+	}
+
+	// 3. 运行 SteamCMD 更新，4. 校验文件
+	updateErr = runServerAppUpdate()
+	result.step("SteamCMD 更新", updateErr, "")
+	after, verifyErr := verifyServerInstall(hadManifest)
+	result.step("校验安装", verifyErr, manifestSummary(after))
+
+	// 文件已被部分覆盖时重新执行一次 validate 修复为完整安装，不使用混合版本的文件启动
+	if verifyErr != nil && beforeErr == nil {
+		repairErr := runServerAppUpdate()
+		if repairErr == nil {
+			after, repairErr = verifyServerInstall(hadManifest)
+		}
+		result.step("修复安装", repairErr, manifestSummary(after))
+		verifyErr = repairErr
+	}
+	if updateErr == nil {
+		updateErr = verifyErr
+	}
+	result.update(func(r *UpdateResult) {
+		r.Updated = updateErr == nil
+		if after != nil {
+			r.NewBuild = after.BuildID
+		}
+	})
+
+	// 5. 恢复运行：仅在安装完整时重启（更新失败但旧文件未受影响时使用旧文件）
+	if result.WasRunning {
+		if verifyErr != nil {
+			result.step("重启服务端", errors.New("安装不完整，已取消重启，请修复后手动启动"), "")
+			return
+		}
+		pid, err := startServerProcess()
+		msg := ""
+		if err == nil {
+			msg = fmt.Sprintf("PID %d", pid)
+			result.update(func(r *UpdateResult) { r.Restarted = true })
+		}
+		result.step("重启服务端", err, msg)
+	}
+}
+
+// manifestSummary 校验步骤显示的构建版本
+func manifestSummary(m *appManifest) string {
+	if m == nil || m.BuildID == "" {
+		return ""
+	}
+	return "构建 " + m.BuildID
+}
+
+// snapshotUpdate 复制最近一次更新流程的进度
+func snapshotUpdate() *UpdateResult {
+	updateStatusMu.Lock()
+	defer updateStatusMu.Unlock()
+	if lastUpdate == nil {
+		return nil
+	}
+	r := *lastUpdate
+	r.Steps = append([]UpdateStep{}, lastUpdate.Steps...)
+	return &r
+}
+
+// GetServerUpdate 获取最近一次更新流程的进度
+func GetServerUpdate(c *gin.Context) {
+	success(c, snapshotUpdate())
+}
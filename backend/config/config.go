@@ -12,8 +12,18 @@ type AppConfig struct {
 	SteamCMDPath  string `json:"steamcmd_path"`
 	ServerPath    string `json:"server_path"`
 	DefaultPreset string `json:"default_preset"`
+
+	// SteamCMD 安装包下载源（为空时使用官方 CDN），以及可选的 SHA-256 校验值
+	SteamCMDMirror string `json:"steamcmd_mirror,omitempty"`
+	SteamCMDSHA256 string `json:"steamcmd_sha256,omitempty"`
+
+	// 定期扫描 addons 目录并同步模组库的间隔（分钟），0 表示关闭
+	AddonScanInterval int `json:"addon_scan_interval,omitempty"`
 }
 
+// DefaultSteamCMDMirror 官方 SteamCMD 安装包下载地址
+const DefaultSteamCMDMirror = "https://steamcdn-a.akamaihd.net/client/installer/"
+
 var (
 	cfg *AppConfig
 	once sync.Once
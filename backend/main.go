@@ -39,6 +39,21 @@ func main() {
 		fmt.Printf("[ARSM] ⚠️ 警告: 正在使用默认密码 (admin/admin)，请尽快修改!\n")
 	}
 
+	// 定期扫描 addons 目录（间隔在设置中配置）
+	api.StartAddonScanner()
+
+	// RCON 长连接保活与自动重连
+	api.StartRCONManager()
+
+	// 记录玩家会话（内嵌数据库位于数据目录）
+	api.StartPlayerTracker()
+
+	// 定时轮播游戏内广播
+	api.StartBroadcastScheduler()
+
+	// 定期移除已过期的名单管理员
+	api.StartRosterExpiry()
+
 	// 生产模式
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
@@ -65,8 +80,10 @@ func main() {
 
 		// 游戏服务端管理
 		authorized.GET("/server/status", api.GetServerStatus)
+		authorized.GET("/server/query", api.GetServerQuery)
 		authorized.POST("/server/install", api.InstallServer)
 		authorized.POST("/server/update", api.UpdateServer)
+		authorized.GET("/server/update", api.GetServerUpdate)
 		authorized.DELETE("/server", api.DeleteServer)
 		authorized.POST("/server/start", api.StartServer)
 		authorized.POST("/server/stop", api.StopServer)
@@ -85,19 +102,95 @@ func main() {
 
 		// 模组管理
 		authorized.GET("/mods", api.GetMods)
+		authorized.GET("/mods/order", api.GetModOrder)
+		authorized.PUT("/mods/order", api.SetModOrder)
+		authorized.POST("/mods/order/auto", api.AutoSortMods)
 		authorized.POST("/mods", api.AddMod)
+		authorized.POST("/mods/import", api.ImportMods)
+		authorized.POST("/mods/scan", api.ScanAddons)
+		authorized.GET("/mods/tags", api.GetModTags)
+		authorized.PUT("/mods/:id", api.UpdateMod)
 		authorized.DELETE("/mods/:id", api.DeleteMod)
 		authorized.POST("/mods/:id/enable", api.EnableMod)
 		authorized.POST("/mods/:id/disable", api.DisableMod)
+		authorized.POST("/mods/:id/move", api.MoveMod)
 		authorized.GET("/mods/:id/check", api.CheckModFiles)
+		authorized.GET("/mods/:id/changelog", api.GetModChangeLog)
+		authorized.GET("/mods/:id/dependencies", api.GetModDependencies)
+		authorized.PUT("/mods/:id/dependencies", api.SetModDependencies)
+
+		// addons 目录占用与清理
+		authorized.GET("/addons/report", api.GetAddonDiskReport)
+		authorized.POST("/addons/cleanup", api.CleanupAddons)
+
+		// 模组包
+		authorized.GET("/modpacks", api.GetModpacks)
+		authorized.POST("/modpacks", api.CreateModpack)
+		authorized.GET("/modpacks/:name", api.GetModpack)
+		authorized.PUT("/modpacks/:name", api.UpdateModpack)
+		authorized.DELETE("/modpacks/:name", api.DeleteModpack)
+		authorized.GET("/modpacks/:name/preview", api.PreviewModpack)
+		authorized.POST("/modpacks/:name/apply", api.ApplyModpack)
 
 		// RCON
 		authorized.GET("/rcon/players", api.GetPlayers)
 		authorized.GET("/rcon/status", api.GetRCONStatus)
 		authorized.GET("/rcon/logs", api.GetRCONLogs)
+		authorized.GET("/rcon/messages", api.GetRCONMessages)
 		authorized.POST("/rcon/kick/:id", api.KickPlayer)
 		authorized.POST("/rcon/ban/:id", api.BanPlayer)
 		authorized.POST("/rcon/command", api.SendRCONCommand)
+		authorized.POST("/rcon/bulk", api.BulkPlayerAction)
+		authorized.GET("/rcon/policy", api.GetRCONPolicies)
+		authorized.PUT("/rcon/policy", api.SaveRCONPolicies)
+		authorized.GET("/rcon/policy/check", api.CheckRCONPolicy)
+		authorized.GET("/rcon/macros", api.GetMacros)
+		authorized.POST("/rcon/macros", api.CreateMacro)
+		authorized.PUT("/rcon/macros/:name", api.UpdateMacro)
+		authorized.DELETE("/rcon/macros/:name", api.DeleteMacro)
+		authorized.POST("/rcon/macros/:name/run", api.RunMacro)
+		authorized.GET("/rcon/reasons", api.GetReasonTemplates)
+		authorized.POST("/rcon/reasons", api.CreateReasonTemplate)
+		authorized.PUT("/rcon/reasons/:name", api.UpdateReasonTemplate)
+		authorized.DELETE("/rcon/reasons/:name", api.DeleteReasonTemplate)
+		authorized.GET("/admins", api.GetServerAdmins)
+		authorized.POST("/admins", api.AddServerAdmin)
+		authorized.DELETE("/admins/:id", api.RemoveServerAdmin)
+		authorized.GET("/admins/presets", api.GetSharedAdminPresets)
+		authorized.PUT("/admins/presets", api.SaveSharedAdminPresets)
+		authorized.GET("/roster", api.GetRoster)
+		authorized.POST("/roster", api.CreateRosterEntry)
+		authorized.PUT("/roster/:identity", api.UpdateRosterEntry)
+		authorized.DELETE("/roster/:identity", api.DeleteRosterEntry)
+		authorized.POST("/roster/apply", api.ApplyRoster)
+		authorized.GET("/roster/export", api.ExportRoster)
+		authorized.POST("/roster/import", api.ImportRoster)
+		authorized.GET("/moderation/rules", api.GetModerationRules)
+		authorized.PUT("/moderation/rules", api.SaveModerationRules)
+		authorized.GET("/moderation/log", api.GetModerationLog)
+
+		// 定时广播
+		authorized.GET("/broadcasts", api.GetBroadcastSets)
+		authorized.POST("/broadcasts", api.CreateBroadcastSet)
+		authorized.PUT("/broadcasts/:name", api.UpdateBroadcastSet)
+		authorized.DELETE("/broadcasts/:name", api.DeleteBroadcastSet)
+		authorized.POST("/broadcasts/:name/send", api.SendBroadcastNow)
+
+		// 封禁管理
+		authorized.GET("/bans", api.GetBans)
+		authorized.POST("/bans", api.CreateBan)
+		authorized.POST("/bans/sync", api.SyncBans)
+		authorized.DELETE("/bans/:id", api.DeleteBan)
+
+		// 审计记录
+		authorized.GET("/audit", api.GetAuditLog)
+
+		// 玩家历史
+		authorized.GET("/players", api.GetPlayerHistory)
+		authorized.GET("/players/sessions", api.GetPlayerSessions)
+		authorized.GET("/players/aliases", api.GetPlayerAliases)
+		authorized.GET("/players/:guid", api.GetPlayerRecord)
+		authorized.GET("/players/:guid/sessions", api.GetPlayerSessions)
 
 		// 设置
 		authorized.GET("/settings", api.GetSettings)
@@ -108,6 +201,15 @@ func main() {
 	// WebSocket 日志
 	r.GET("/ws/logs", ws.HandleLogs)
 
+	// WebSocket RCON 服务端消息（聊天、进出、BattlEye 事件）
+	r.GET("/ws/rcon", api.HandleRCONMessages)
+
+	// WebSocket RCON 命令记录
+	r.GET("/ws/rcon/logs", api.HandleRCONLogs)
+
+	// WebSocket 服务端状态变化
+	r.GET("/ws/server", api.HandleServerState)
+
 	// 静态文件服务
 	staticFS, _ := fs.Sub(staticFiles, "static")
 	r.NoRoute(gin.WrapH(http.FileServer(http.FS(staticFS))))
@@ -16,10 +16,64 @@ type SystemInfo struct {
 
 // ServerStatus 服务端状态
 type ServerStatus struct {
-	Installed bool   `json:"installed"`
-	Running   bool   `json:"running"`
-	PID       int    `json:"pid,omitempty"`
-	Version   string `json:"version,omitempty"`
+	Installed bool         `json:"installed"`
+	Running   bool         `json:"running"`
+	Ready     bool         `json:"ready"` // 可以接受玩家（state 为 ready）
+	State     string       `json:"state"` // stopped / starting / loading / ready / stopping / crashed
+	StateInfo string       `json:"state_detail,omitempty"`
+	Since     int64        `json:"state_since,omitempty"`
+	PID       int          `json:"pid,omitempty"`
+	Version   string       `json:"version,omitempty"`
+	Query     *ServerQuery `json:"query,omitempty"` // 运行时的 A2S 查询结果（不含玩家列表）
+}
+
+// ServerStateEvent 服务端状态变化，通过 WebSocket 推送
+type ServerStateEvent struct {
+	State    string `json:"state"`
+	Previous string `json:"previous,omitempty"`
+	Detail   string `json:"detail,omitempty"` // 如崩溃原因、识别到的日志
+	Since    int64  `json:"since"`
+}
+
+// ServerQuery A2S 查询结果
+type ServerQuery struct {
+	Address   string         `json:"address"`
+	Online    bool           `json:"online"` // 是否响应 A2S_INFO
+	LatencyMs int64          `json:"latency_ms,omitempty"`
+	Info      *ServerInfo    `json:"info,omitempty"`
+	Players   []ServerPlayer `json:"players,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	QueriedAt int64          `json:"queried_at"`
+}
+
+// ServerInfo A2S_INFO 返回的服务端信息
+type ServerInfo struct {
+	Protocol    uint8  `json:"protocol"`
+	Name        string `json:"name"`
+	Map         string `json:"map"`
+	Folder      string `json:"folder"`
+	Game        string `json:"game"`
+	AppID       uint16 `json:"app_id"`
+	Players     int    `json:"players"`
+	MaxPlayers  int    `json:"max_players"`
+	Bots        int    `json:"bots"`
+	ServerType  string `json:"server_type"` // d: 独立服务端 / l: 非独立 / p: SourceTV
+	Environment string `json:"environment"` // l: Linux / w: Windows / m: macOS
+	Password    bool   `json:"password"`
+	VAC         bool   `json:"vac"`
+	Version     string `json:"version"`
+	Port        uint16 `json:"port,omitempty"`
+	SteamID     uint64 `json:"steam_id,omitempty"`
+	Keywords    string `json:"keywords,omitempty"`
+	GameID      uint64 `json:"game_id,omitempty"`
+}
+
+// ServerPlayer A2S_PLAYER 返回的一名玩家
+type ServerPlayer struct {
+	Index    uint8   `json:"index"`
+	Name     string  `json:"name"`
+	Score    int32   `json:"score"`
+	Duration float32 `json:"duration"` // 在线秒数
 }
 
 // SteamCMDStatus SteamCMD状态
@@ -36,16 +90,304 @@ type Mod struct {
 	Version     string `json:"version"`
 	Enabled     bool   `json:"enabled"`
 	Downloaded  bool   `json:"downloaded"`
+
+	// 手动录入的依赖模组 ID，与 addon 元数据中声明的依赖合并使用
+	Dependencies []string `json:"dependencies,omitempty"`
+	// 扫描 addons 目录时发现文件已缺失
+	Orphaned bool `json:"orphaned,omitempty"`
+	// 最后一次出现在 config.json 启用列表中的时间
+	LastEnabledAt int64 `json:"last_enabled_at,omitempty"`
+
+	// 管理备注
+	Notes       string   `json:"notes,omitempty"`
+	RequestedBy string   `json:"requested_by,omitempty"` // 谁要求安装的
+	Tags        []string `json:"tags,omitempty"`
+	AddedBy     string   `json:"added_by,omitempty"` // 添加到模组库的 ARSM 用户
+	AddedAt     int64    `json:"added_at,omitempty"`
+
+	// 磁盘更新跟踪：最后一次在磁盘上看到的版本及其更新时间、版本变更记录
+	DiskVersion   string      `json:"disk_version,omitempty"`
+	DiskUpdatedAt int64       `json:"disk_updated_at,omitempty"`
+	ChangeLog     []ModChange `json:"change_log,omitempty"`
+}
+
+// ModView 模组列表响应，附加由磁盘上的 addon 计算得出的字段（不写入模组库）
+type ModView struct {
+	Mod
+	InstalledVersion string `json:"installed_version,omitempty"`
+	Size             int64  `json:"size,omitempty"`           // 字节
+	LastModified     int64  `json:"last_modified,omitempty"`  // Unix 时间戳
+	PinnedVersion    string `json:"pinned_version,omitempty"` // config.json 中锁定的版本
+	VersionMismatch  bool   `json:"version_mismatch,omitempty"`
+}
+
+// ModChange 磁盘上检测到的模组版本变更
+type ModChange struct {
+	Time       int64  `json:"time"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+}
+
+// AddonInfo 磁盘上 addon 的状态
+type AddonInfo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Path         string `json:"path"`
+	Size         int64  `json:"size"`
+	LastModified int64  `json:"last_modified"`
+	HasMetadata  bool   `json:"has_metadata"`
 }
 
 // Player 玩家信息
 type Player struct {
-	ID         string `json:"id"`
+	ID         string `json:"id"` // 服务端会话编号
 	Name       string `json:"name"`
+	GUID       string `json:"guid,omitempty"`        // BattlEye GUID
+	IdentityID string `json:"identity_id,omitempty"` // Bohemia 身份 ID
+	PlatformID string `json:"platform_id,omitempty"` // Steam64 等平台 ID
+	IP         string `json:"ip,omitempty"`          // 非管理员只能看到部分 IP
+	Port       int    `json:"port,omitempty"`
+	Ping       int    `json:"ping"`
+	Lobby      bool   `json:"lobby,omitempty"` // 仍在大厅（未进入游戏）
 	Online     bool   `json:"online"`
 	OnlineTime int64  `json:"online_time"` // 在线时长（秒）
 }
 
+// PingSample 某一时刻的延迟采样
+type PingSample struct {
+	Time int64 `json:"time"`
+	Ping int   `json:"ping"`
+}
+
+// PlayerSession 玩家的一次在线会话
+type PlayerSession struct {
+	ID          uint64       `json:"id"`
+	GUID        string       `json:"guid,omitempty"` // BattlEye GUID，没有时为身份 ID 或平台 ID
+	PlayerID    string       `json:"player_id"`      // 服务端会话编号
+	Name        string       `json:"name"`
+	IP          string       `json:"ip,omitempty"`
+	Port        int          `json:"port,omitempty"`
+	JoinedAt    int64        `json:"joined_at"`
+	LeftAt      int64        `json:"left_at,omitempty"` // 0 表示仍在线
+	LastSeen    int64        `json:"last_seen"`
+	Duration    int64        `json:"duration"` // 秒
+	PingSamples []PingSample `json:"ping_samples,omitempty"`
+	PingAvg     int          `json:"ping_avg,omitempty"`
+	PingMax     int          `json:"ping_max,omitempty"`
+}
+
+// PlayerAlias 玩家使用过的名称或 IP
+type PlayerAlias struct {
+	Value     string `json:"value"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+	Count     int    `json:"count"` // 会话次数
+}
+
+// PlayerRecord 按玩家标识（GUID / 身份 ID / 平台 ID）汇总的玩家历史
+type PlayerRecord struct {
+	GUID         string        `json:"guid"`
+	Name         string        `json:"name"` // 最近使用的名称
+	Names        []PlayerAlias `json:"names"`
+	IPs          []PlayerAlias `json:"ips,omitempty"`
+	FirstSeen    int64         `json:"first_seen"`
+	LastSeen     int64         `json:"last_seen"`
+	SessionCount int           `json:"session_count"`
+	TotalOnline  int64         `json:"total_online"` // 累计在线时长（秒），包含当前会话
+	Online       bool          `json:"online"`
+}
+
+// Ban 封禁记录
+type Ban struct {
+	ID        uint64 `json:"id"`
+	Identity  string `json:"identity,omitempty"`  // GUID / 身份 ID，按会话编号封禁且无法识别时为空
+	ServerID  string `json:"server_id,omitempty"` // 服务端 bans 列表中显示的标识（GUID），与 Identity 不同时记录
+	Name      string `json:"name,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	Reason    string `json:"reason"`
+	Duration  int    `json:"duration"` // 分钟，0 表示永久
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at,omitempty"` // 0 表示永久
+	IssuedBy  string `json:"issued_by"`
+	Source    string `json:"source"` // arsm / server（从服务端封禁列表导入）
+	RevokedAt int64  `json:"revoked_at,omitempty"`
+	RevokedBy string `json:"revoked_by,omitempty"`
+	Status    string `json:"status"` // active / expired / revoked，读取时计算
+}
+
+// BanSyncResult 与服务端封禁列表同步的结果
+type BanSyncResult struct {
+	Reapplied []uint64 `json:"reapplied"` // 服务端缺失、已重新下发的 ARSM 封禁
+	Imported  []uint64 `json:"imported"`  // 从服务端导入的封禁
+	Removed   []string `json:"removed"`   // 已在 ARSM 解封或已过期、从服务端移除的标识
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ReasonTemplate 踢出 / 封禁原因模板
+type ReasonTemplate struct {
+	Name     string `json:"name"`
+	Reason   string `json:"reason"`
+	Action   string `json:"action,omitempty"`   // kick / ban，为空表示通用
+	Duration int    `json:"duration,omitempty"` // 封禁时的默认时长（分钟），0 表示永久
+}
+
+// PlayerActionResult 批量操作中单个玩家的执行结果
+type PlayerActionResult struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name,omitempty"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	BanID    uint64 `json:"ban_id,omitempty"`
+}
+
+// BroadcastSet 定时轮播的游戏内广播消息组
+type BroadcastSet struct {
+	Name       string   `json:"name"`
+	Messages   []string `json:"messages"`
+	Interval   int      `json:"interval"` // 分钟，每次发送其中一条，按顺序轮换
+	Enabled    bool     `json:"enabled"`
+	ActiveFrom string   `json:"active_from,omitempty"` // HH:MM，与 active_to 均为空表示全天
+	ActiveTo   string   `json:"active_to,omitempty"`   // HH:MM，早于 active_from 时跨越午夜
+
+	// 运行状态（不保存到文件）
+	LastSentAt  int64  `json:"last_sent_at,omitempty"`
+	NextIndex   int    `json:"next_index,omitempty"`
+	PauseReason string `json:"pause_reason,omitempty"` // disabled / inactive_hours / server_down / empty
+}
+
+// MacroParam RCON 宏参数，在命令中以 {name} 引用
+type MacroParam struct {
+	Name     string `json:"name"`
+	Label    string `json:"label,omitempty"`
+	Type     string `json:"type,omitempty"` // string / int / player（在线玩家会话编号）
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// RCONMacro 命名的 RCON 命令序列
+type RCONMacro struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Steps       []string     `json:"steps"`
+	Params      []MacroParam `json:"params,omitempty"`
+	Roles       []string     `json:"roles,omitempty"` // 可见并可执行的角色，为空表示所有角色
+	StopOnError bool         `json:"stop_on_error,omitempty"`
+}
+
+// MacroStepResult 宏中单条命令的执行结果
+type MacroStepResult struct {
+	Command    string `json:"command"`
+	Response   string `json:"response,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Skipped    bool   `json:"skipped,omitempty"`
+}
+
+// AuditEntry 操作审计记录
+type AuditEntry struct {
+	ID      uint64 `json:"id"`
+	Time    int64  `json:"time"`
+	User    string `json:"user"`
+	Action  string `json:"action"` // 如 macro.run
+	Target  string `json:"target,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Success bool   `json:"success"`
+}
+
+// RosterEntry 受信任玩家名单条目，由 ARSM 写入 config.json
+type RosterEntry struct {
+	Identity  string `json:"identity"` // Bohemia 身份 ID 或 Steam64 ID
+	Name      string `json:"name"`
+	Note      string `json:"note,omitempty"`
+	Admin     bool   `json:"admin"`                // 写入 game.admins
+	ExpiresAt int64  `json:"expires_at,omitempty"` // 0 表示永不过期
+	AddedBy   string `json:"added_by,omitempty"`
+	AddedAt   int64  `json:"added_at"`
+	Expired   bool   `json:"expired,omitempty"` // 运行状态，不写入文件
+}
+
+// ServerAdmin 服务端管理员（game.admins）
+type ServerAdmin struct {
+	ID        string `json:"id"` // Steam64 ID 或 Bohemia 身份 ID
+	Name      string `json:"name,omitempty"`
+	Note      string `json:"note,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Expired   bool   `json:"expired,omitempty"`
+	Managed   bool   `json:"managed"`             // false 表示在 config.json 中手动添加
+	Invalid   bool   `json:"invalid,omitempty"`   // 手动添加的 ID 格式无效
+	Duplicate bool   `json:"duplicate,omitempty"` // 手动添加的 ID 重复
+}
+
+// RosterImportResult CSV 导入结果
+type RosterImportResult struct {
+	Added   int      `json:"added"`
+	Updated int      `json:"updated"`
+	Errors  []string `json:"errors"`
+}
+
+// ModerationRule 自动管理规则，根据定时获取的玩家列表判断
+type ModerationRule struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // ping / name / idle
+	Enabled     bool     `json:"enabled"`
+	DryRun      bool     `json:"dry_run"`                // 只记录将执行的操作，不实际执行
+	MaxPing     int      `json:"max_ping,omitempty"`     // ping: 延迟阈值（毫秒）
+	Samples     int      `json:"samples,omitempty"`      // ping: 连续超过阈值的采样次数
+	Words       []string `json:"words,omitempty"`        // name: 屏蔽词，包含匹配，不区分大小写
+	Pattern     string   `json:"pattern,omitempty"`      // name: 名称正则，不区分大小写
+	WarnAfter   int      `json:"warn_after,omitempty"`   // idle: 停留大厅多少分钟后警告，0 表示不警告
+	KickAfter   int      `json:"kick_after,omitempty"`   // idle: 停留大厅多少分钟后踢出，0 表示不踢出
+	WarnMessage string   `json:"warn_message,omitempty"` // idle: 警告内容
+	Reason      string   `json:"reason,omitempty"`       // 踢出原因
+}
+
+// ModerationAction 自动管理执行（或试运行）的操作
+type ModerationAction struct {
+	ID         uint64 `json:"id"`
+	Time       int64  `json:"time"`
+	Rule       string `json:"rule"`
+	Action     string `json:"action"` // warn / kick
+	PlayerID   string `json:"player_id"`
+	PlayerName string `json:"player_name"`
+	Identity   string `json:"identity,omitempty"`
+	Detail     string `json:"detail"`
+	DryRun     bool   `json:"dry_run"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RCONLogEntry 一条 RCON 命令记录
+type RCONLogEntry struct {
+	ID         uint64 `json:"id"`
+	Time       int64  `json:"time"`
+	User       string `json:"user"` // system 表示 ARSM 自动下发
+	Command    string `json:"command"`
+	Response   string `json:"response"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// RCONPolicy 某个 ARSM 角色允许执行的 RCON 命令
+type RCONPolicy struct {
+	Role  string   `json:"role"`
+	Allow []string `json:"allow"`          // 命令模式，命中任一即允许
+	Deny  []string `json:"deny,omitempty"` // 优先于 allow
+}
+
+// RCONMessage 服务端通过 RCON 主动推送的消息
+type RCONMessage struct {
+	ID         int64  `json:"id"`
+	Time       int64  `json:"time"`
+	Type       string `json:"type"` // connect / disconnect / guid / chat / kick / admin / other
+	Raw        string `json:"raw"`
+	PlayerID   string `json:"player_id,omitempty"`
+	PlayerName string `json:"player_name,omitempty"`
+	GUID       string `json:"guid,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	Channel    string `json:"channel,omitempty"` // 聊天频道
+	Text       string `json:"text,omitempty"`    // 聊天内容或踢出原因
+}
+
 // ServerConfig Arma Reforger 服务器配置
 type ServerConfig struct {
 	BindAddress   string          `json:"bindAddress"`
@@ -104,6 +446,39 @@ type ModConfig struct {
 	Version string `json:"version,omitempty"`
 }
 
+// Modpack 模组包：有序的模组列表（可锁定版本），可整体应用到 game.mods
+type Modpack struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Mods        []ModConfig `json:"mods"`
+	CreatedAt   int64       `json:"created_at"`
+	UpdatedAt   int64       `json:"updated_at"`
+}
+
+// AddonUsage addon 磁盘占用及引用情况
+type AddonUsage struct {
+	AddonInfo
+	Enabled       bool     `json:"enabled"`
+	LastEnabledAt int64    `json:"last_enabled_at,omitempty"`
+	ReferencedBy  []string `json:"referenced_by"` // config.json / preset:<名称> / modpack:<名称> / dependency:<模组ID>
+}
+
+// AddonDiskReport addons 目录占用报告
+type AddonDiskReport struct {
+	Addons     []AddonUsage `json:"addons"`
+	TotalSize  int64        `json:"total_size"`
+	UnusedSize int64        `json:"unused_size"`
+}
+
+// ModpackDiff 应用模组包前后 game.mods 的差异
+type ModpackDiff struct {
+	Added          []ModConfig `json:"added"`
+	Removed        []ModConfig `json:"removed"`
+	VersionChanged []ModConfig `json:"version_changed"` // 新的锁定版本
+	Unchanged      []ModConfig `json:"unchanged"`
+	OrderChanged   bool        `json:"order_changed"`
+}
+
 type OperatingConfig struct {
 	LobbyPlayerSynchronise  bool            `json:"lobbyPlayerSynchronise"`
 	JoinQueue               JoinQueueConfig `json:"joinQueue"`
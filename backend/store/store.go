@@ -0,0 +1,104 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 内嵌数据库（bbolt），保存玩家历史等会持续增长、不适合整体读写 JSON 文件的数据
+
+var (
+	db     *bolt.DB
+	dbErr  error
+	dbOnce sync.Once
+)
+
+// getDBPath 数据库文件位于 ARSM 数据目录（与 users.json 相同）
+func getDBPath() string {
+	dataDir := "./data"
+	if envDir := os.Getenv("ARSM_DATA_DIR"); envDir != "" {
+		dataDir = envDir
+	}
+	return filepath.Join(dataDir, "arsm.db")
+}
+
+// DB 返回数据库实例，首次调用时打开
+func DB() (*bolt.DB, error) {
+	dbOnce.Do(func() {
+		path := getDBPath()
+		if dbErr = os.MkdirAll(filepath.Dir(path), 0755); dbErr != nil {
+			return
+		}
+		// 另一个 ARSM 实例持有文件锁时不无限等待
+		db, dbErr = bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	})
+	return db, dbErr
+}
+
+// Close 关闭数据库
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	return db.Close()
+}
+
+// Update 在读写事务中执行 fn
+func Update(fn func(tx *bolt.Tx) error) error {
+	d, err := DB()
+	if err != nil {
+		return err
+	}
+	return d.Update(fn)
+}
+
+// View 在只读事务中执行 fn
+func View(fn func(tx *bolt.Tx) error) error {
+	d, err := DB()
+	if err != nil {
+		return err
+	}
+	return d.View(fn)
+}
+
+// Itob 将序号编码为按大小排序的键
+func Itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// Btoi 解码 Itob 生成的键
+func Btoi(b []byte) uint64 {
+	if len(b) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// PutJSON 将 v 序列化后写入 bucket
+func PutJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// GetJSON 读取 bucket 中的 JSON 值，键不存在时返回 false
+func GetJSON(b *bolt.Bucket, key []byte, v interface{}) (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+	data := b.Get(key)
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
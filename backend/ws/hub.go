@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	hubSendBuffer   = 256              // 每个客户端待发送消息的缓冲数量，写满视为客户端过慢
+	hubWriteTimeout = 10 * time.Second // 单条消息的写超时
+)
+
+// hubClient 一个连接及其待发送的消息，由独立的 goroutine 写出
+type hubClient struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// Hub 一组 WebSocket 客户端，用于推送某一类 JSON 消息（与控制台日志分开）
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*hubClient]bool
+}
+
+// NewHub 创建消息推送 Hub
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*hubClient]bool)}
+}
+
+// BroadcastJSON 发送 JSON 消息到所有连接的客户端；只放入各客户端的发送队列，不会阻塞调用方，
+// 队列已满的客户端直接断开
+func (h *Hub) BroadcastJSON(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		select {
+		case client.send <- data:
+		default:
+			h.removeLocked(client)
+			client.conn.Close()
+		}
+	}
+}
+
+// removeLocked 注销客户端并关闭发送队列，调用方需持有 h.mu
+func (h *Hub) removeLocked(client *hubClient) {
+	if h.clients[client] {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
+// writeLoop 写出发送队列中的消息，写入失败或超时时关闭连接
+func (client *hubClient) writeLoop() {
+	for data := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+		if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			client.conn.Close()
+			// 继续读取直到队列关闭，避免 BroadcastJSON 写入已无人读取的队列
+			for range client.send {
+			}
+			return
+		}
+	}
+}
+
+// Handle 处理 WebSocket 连接，连接后先发送 backlog 中的历史消息
+func (h *Hub) Handle(c *gin.Context, backlog []interface{}) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	client := &hubClient{conn: conn, send: make(chan []byte, hubSendBuffer+len(backlog))}
+
+	// 历史消息先放入队列再注册客户端，持有锁保证历史与实时消息不交错
+	h.mu.Lock()
+	for _, v := range backlog {
+		if data, err := json.Marshal(v); err == nil {
+			client.send <- data
+		}
+	}
+	h.clients[client] = true
+	h.mu.Unlock()
+
+	go client.writeLoop()
+
+	// 保持连接
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			h.mu.Lock()
+			h.removeLocked(client)
+			h.mu.Unlock()
+			break
+		}
+	}
+}
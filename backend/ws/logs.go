@@ -70,6 +70,11 @@ func watchLogFile() {
 
 	for {
 		cfg := config.Get()
+		if cfg == nil {
+			// init 中启动，main 可能还未加载配置
+			time.Sleep(1 * time.Second)
+			continue
+		}
 		logDir := filepath.Join(cfg.ServerPath, "profile", "logs") // Reforger with -profile puts logs in profile/logs
 		// 某些配置可能在 profile 目录下
 		// 这里假设用户配置的 ServerPath 是根目录，logs 在其中